@@ -405,6 +405,108 @@ func (x *RoomsReply) GetRooms() map[string]bool {
 	return nil
 }
 
+type KickReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Keys   []string `protobuf:"bytes,1,rep,name=keys,proto3" json:"keys,omitempty"`
+	Reason string   `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+}
+
+func (x *KickReq) Reset() {
+	*x = KickReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_connect_connect_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *KickReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KickReq) ProtoMessage() {}
+
+func (x *KickReq) ProtoReflect() protoreflect.Message {
+	mi := &file_connect_connect_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KickReq.ProtoReflect.Descriptor instead.
+func (*KickReq) Descriptor() ([]byte, []int) {
+	return file_connect_connect_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *KickReq) GetKeys() []string {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+func (x *KickReq) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type KickReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Kicked int32 `protobuf:"varint,1,opt,name=kicked,proto3" json:"kicked,omitempty"`
+}
+
+func (x *KickReply) Reset() {
+	*x = KickReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_connect_connect_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *KickReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KickReply) ProtoMessage() {}
+
+func (x *KickReply) ProtoReflect() protoreflect.Message {
+	mi := &file_connect_connect_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KickReply.ProtoReflect.Descriptor instead.
+func (*KickReply) Descriptor() ([]byte, []int) {
+	return file_connect_connect_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *KickReply) GetKicked() int32 {
+	if x != nil {
+		return x.Kicked
+	}
+	return 0
+}
+
 var File_connect_connect_proto protoreflect.FileDescriptor
 
 var file_connect_connect_proto_rawDesc = []byte{
@@ -442,24 +544,33 @@ var file_connect_connect_proto_rawDesc = []byte{
 	0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20,
 	0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75,
 	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02,
-	0x38, 0x01, 0x32, 0xf5, 0x01, 0x0a, 0x05, 0x43, 0x6f, 0x6d, 0x65, 0x74, 0x12, 0x35, 0x0a, 0x07,
-	0x50, 0x75, 0x73, 0x68, 0x4d, 0x73, 0x67, 0x12, 0x13, 0x2e, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63,
-	0x74, 0x2e, 0x50, 0x75, 0x73, 0x68, 0x4d, 0x73, 0x67, 0x52, 0x65, 0x71, 0x1a, 0x15, 0x2e, 0x63,
-	0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x2e, 0x50, 0x75, 0x73, 0x68, 0x4d, 0x73, 0x67, 0x52, 0x65,
-	0x70, 0x6c, 0x79, 0x12, 0x3b, 0x0a, 0x09, 0x42, 0x72, 0x6f, 0x61, 0x64, 0x63, 0x61, 0x73, 0x74,
-	0x12, 0x15, 0x2e, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x2e, 0x42, 0x72, 0x6f, 0x61, 0x64,
-	0x63, 0x61, 0x73, 0x74, 0x52, 0x65, 0x71, 0x1a, 0x17, 0x2e, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63,
-	0x74, 0x2e, 0x42, 0x72, 0x6f, 0x61, 0x64, 0x63, 0x61, 0x73, 0x74, 0x52, 0x65, 0x70, 0x6c, 0x79,
-	0x12, 0x47, 0x0a, 0x0d, 0x42, 0x72, 0x6f, 0x61, 0x64, 0x63, 0x61, 0x73, 0x74, 0x52, 0x6f, 0x6f,
-	0x6d, 0x12, 0x19, 0x2e, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x2e, 0x42, 0x72, 0x6f, 0x61,
-	0x64, 0x63, 0x61, 0x73, 0x74, 0x52, 0x6f, 0x6f, 0x6d, 0x52, 0x65, 0x71, 0x1a, 0x1b, 0x2e, 0x63,
+	0x38, 0x01, 0x22, 0x35, 0x0a, 0x07, 0x4b, 0x69, 0x63, 0x6b, 0x52, 0x65, 0x71, 0x12, 0x12, 0x0a,
+	0x04, 0x6b, 0x65, 0x79, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x6b, 0x65, 0x79,
+	0x73, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x22, 0x23, 0x0a, 0x09, 0x4b, 0x69, 0x63,
+	0x6b, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x16, 0x0a, 0x06, 0x6b, 0x69, 0x63, 0x6b, 0x65, 0x64,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x6b, 0x69, 0x63, 0x6b, 0x65, 0x64, 0x32, 0xa3,
+	0x02, 0x0a, 0x05, 0x43, 0x6f, 0x6d, 0x65, 0x74, 0x12, 0x35, 0x0a, 0x07, 0x50, 0x75, 0x73, 0x68,
+	0x4d, 0x73, 0x67, 0x12, 0x13, 0x2e, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x2e, 0x50, 0x75,
+	0x73, 0x68, 0x4d, 0x73, 0x67, 0x52, 0x65, 0x71, 0x1a, 0x15, 0x2e, 0x63, 0x6f, 0x6e, 0x6e, 0x65,
+	0x63, 0x74, 0x2e, 0x50, 0x75, 0x73, 0x68, 0x4d, 0x73, 0x67, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12,
+	0x3b, 0x0a, 0x09, 0x42, 0x72, 0x6f, 0x61, 0x64, 0x63, 0x61, 0x73, 0x74, 0x12, 0x15, 0x2e, 0x63,
 	0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x2e, 0x42, 0x72, 0x6f, 0x61, 0x64, 0x63, 0x61, 0x73, 0x74,
-	0x52, 0x6f, 0x6f, 0x6d, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x2f, 0x0a, 0x05, 0x52, 0x6f, 0x6f,
-	0x6d, 0x73, 0x12, 0x11, 0x2e, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x2e, 0x52, 0x6f, 0x6f,
-	0x6d, 0x73, 0x52, 0x65, 0x71, 0x1a, 0x13, 0x2e, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x2e,
-	0x52, 0x6f, 0x6f, 0x6d, 0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x42, 0x1b, 0x5a, 0x19, 0x67, 0x6f,
-	0x2d, 0x69, 0x6d, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x3b,
-	0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x52, 0x65, 0x71, 0x1a, 0x17, 0x2e, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x2e, 0x42, 0x72,
+	0x6f, 0x61, 0x64, 0x63, 0x61, 0x73, 0x74, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x47, 0x0a, 0x0d,
+	0x42, 0x72, 0x6f, 0x61, 0x64, 0x63, 0x61, 0x73, 0x74, 0x52, 0x6f, 0x6f, 0x6d, 0x12, 0x19, 0x2e,
+	0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x2e, 0x42, 0x72, 0x6f, 0x61, 0x64, 0x63, 0x61, 0x73,
+	0x74, 0x52, 0x6f, 0x6f, 0x6d, 0x52, 0x65, 0x71, 0x1a, 0x1b, 0x2e, 0x63, 0x6f, 0x6e, 0x6e, 0x65,
+	0x63, 0x74, 0x2e, 0x42, 0x72, 0x6f, 0x61, 0x64, 0x63, 0x61, 0x73, 0x74, 0x52, 0x6f, 0x6f, 0x6d,
+	0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x2f, 0x0a, 0x05, 0x52, 0x6f, 0x6f, 0x6d, 0x73, 0x12, 0x11,
+	0x2e, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x2e, 0x52, 0x6f, 0x6f, 0x6d, 0x73, 0x52, 0x65,
+	0x71, 0x1a, 0x13, 0x2e, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x2e, 0x52, 0x6f, 0x6f, 0x6d,
+	0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x2c, 0x0a, 0x04, 0x4b, 0x69, 0x63, 0x6b, 0x12, 0x10,
+	0x2e, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x2e, 0x4b, 0x69, 0x63, 0x6b, 0x52, 0x65, 0x71,
+	0x1a, 0x12, 0x2e, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x2e, 0x4b, 0x69, 0x63, 0x6b, 0x52,
+	0x65, 0x70, 0x6c, 0x79, 0x42, 0x1b, 0x5a, 0x19, 0x67, 0x6f, 0x2d, 0x69, 0x6d, 0x2f, 0x61, 0x70,
+	0x69, 0x2f, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x3b, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63,
+	0x74, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -474,7 +585,7 @@ func file_connect_connect_proto_rawDescGZIP() []byte {
 	return file_connect_connect_proto_rawDescData
 }
 
-var file_connect_connect_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_connect_connect_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
 var file_connect_connect_proto_goTypes = []interface{}{
 	(*PushMsgReq)(nil),         // 0: connect.PushMsgReq
 	(*PushMsgReply)(nil),       // 1: connect.PushMsgReply
@@ -484,27 +595,31 @@ var file_connect_connect_proto_goTypes = []interface{}{
 	(*BroadcastRoomReply)(nil), // 5: connect.BroadcastRoomReply
 	(*RoomsReq)(nil),           // 6: connect.RoomsReq
 	(*RoomsReply)(nil),         // 7: connect.RoomsReply
-	nil,                        // 8: connect.RoomsReply.RoomsEntry
-	(*protocol.Proto)(nil),     // 9: protocol.Proto
+	(*KickReq)(nil),            // 8: connect.KickReq
+	(*KickReply)(nil),          // 9: connect.KickReply
+	nil,                        // 10: connect.RoomsReply.RoomsEntry
+	(*protocol.Proto)(nil),     // 11: protocol.Proto
 }
 var file_connect_connect_proto_depIdxs = []int32{
-	9, // 0: connect.PushMsgReq.proto:type_name -> protocol.Proto
-	9, // 1: connect.BroadcastReq.proto:type_name -> protocol.Proto
-	9, // 2: connect.BroadcastRoomReq.proto:type_name -> protocol.Proto
-	8, // 3: connect.RoomsReply.rooms:type_name -> connect.RoomsReply.RoomsEntry
-	0, // 4: connect.Comet.PushMsg:input_type -> connect.PushMsgReq
-	2, // 5: connect.Comet.Broadcast:input_type -> connect.BroadcastReq
-	4, // 6: connect.Comet.BroadcastRoom:input_type -> connect.BroadcastRoomReq
-	6, // 7: connect.Comet.Rooms:input_type -> connect.RoomsReq
-	1, // 8: connect.Comet.PushMsg:output_type -> connect.PushMsgReply
-	3, // 9: connect.Comet.Broadcast:output_type -> connect.BroadcastReply
-	5, // 10: connect.Comet.BroadcastRoom:output_type -> connect.BroadcastRoomReply
-	7, // 11: connect.Comet.Rooms:output_type -> connect.RoomsReply
-	8, // [8:12] is the sub-list for method output_type
-	4, // [4:8] is the sub-list for method input_type
-	4, // [4:4] is the sub-list for extension type_name
-	4, // [4:4] is the sub-list for extension extendee
-	0, // [0:4] is the sub-list for field type_name
+	11, // 0: connect.PushMsgReq.proto:type_name -> protocol.Proto
+	11, // 1: connect.BroadcastReq.proto:type_name -> protocol.Proto
+	11, // 2: connect.BroadcastRoomReq.proto:type_name -> protocol.Proto
+	10, // 3: connect.RoomsReply.rooms:type_name -> connect.RoomsReply.RoomsEntry
+	0,  // 4: connect.Comet.PushMsg:input_type -> connect.PushMsgReq
+	2,  // 5: connect.Comet.Broadcast:input_type -> connect.BroadcastReq
+	4,  // 6: connect.Comet.BroadcastRoom:input_type -> connect.BroadcastRoomReq
+	6,  // 7: connect.Comet.Rooms:input_type -> connect.RoomsReq
+	8,  // 8: connect.Comet.Kick:input_type -> connect.KickReq
+	1,  // 9: connect.Comet.PushMsg:output_type -> connect.PushMsgReply
+	3,  // 10: connect.Comet.Broadcast:output_type -> connect.BroadcastReply
+	5,  // 11: connect.Comet.BroadcastRoom:output_type -> connect.BroadcastRoomReply
+	7,  // 12: connect.Comet.Rooms:output_type -> connect.RoomsReply
+	9,  // 13: connect.Comet.Kick:output_type -> connect.KickReply
+	9,  // [9:14] is the sub-list for method output_type
+	4,  // [4:9] is the sub-list for method input_type
+	4,  // [4:4] is the sub-list for extension type_name
+	4,  // [4:4] is the sub-list for extension extendee
+	0,  // [0:4] is the sub-list for field type_name
 }
 
 func init() { file_connect_connect_proto_init() }
@@ -609,6 +724,30 @@ func file_connect_connect_proto_init() {
 				return nil
 			}
 		}
+		file_connect_connect_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*KickReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_connect_connect_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*KickReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -616,7 +755,7 @@ func file_connect_connect_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_connect_connect_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   9,
+			NumMessages:   11,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -650,6 +789,8 @@ type CometClient interface {
 	BroadcastRoom(ctx context.Context, in *BroadcastRoomReq, opts ...grpc.CallOption) (*BroadcastRoomReply, error)
 	// Rooms get all rooms
 	Rooms(ctx context.Context, in *RoomsReq, opts ...grpc.CallOption) (*RoomsReply, error)
+	// Kick send OpKicked to the keys then close them
+	Kick(ctx context.Context, in *KickReq, opts ...grpc.CallOption) (*KickReply, error)
 }
 
 type cometClient struct {
@@ -696,6 +837,15 @@ func (c *cometClient) Rooms(ctx context.Context, in *RoomsReq, opts ...grpc.Call
 	return out, nil
 }
 
+func (c *cometClient) Kick(ctx context.Context, in *KickReq, opts ...grpc.CallOption) (*KickReply, error) {
+	out := new(KickReply)
+	err := c.cc.Invoke(ctx, "/connect.Comet/Kick", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // CometServer is the server API for Comet service.
 type CometServer interface {
 	// PushMsg push by key or mid
@@ -706,6 +856,8 @@ type CometServer interface {
 	BroadcastRoom(context.Context, *BroadcastRoomReq) (*BroadcastRoomReply, error)
 	// Rooms get all rooms
 	Rooms(context.Context, *RoomsReq) (*RoomsReply, error)
+	// Kick send OpKicked to the keys then close them
+	Kick(context.Context, *KickReq) (*KickReply, error)
 }
 
 // UnimplementedCometServer can be embedded to have forward compatible implementations.
@@ -724,6 +876,9 @@ func (*UnimplementedCometServer) BroadcastRoom(context.Context, *BroadcastRoomRe
 func (*UnimplementedCometServer) Rooms(context.Context, *RoomsReq) (*RoomsReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Rooms not implemented")
 }
+func (*UnimplementedCometServer) Kick(context.Context, *KickReq) (*KickReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Kick not implemented")
+}
 
 func RegisterCometServer(s *grpc.Server, srv CometServer) {
 	s.RegisterService(&_Comet_serviceDesc, srv)
@@ -801,6 +956,24 @@ func _Comet_Rooms_Handler(srv interface{}, ctx context.Context, dec func(interfa
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Comet_Kick_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KickReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CometServer).Kick(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/connect.Comet/Kick",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CometServer).Kick(ctx, req.(*KickReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Comet_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "connect.Comet",
 	HandlerType: (*CometServer)(nil),
@@ -821,6 +994,10 @@ var _Comet_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Rooms",
 			Handler:    _Comet_Rooms_Handler,
 		},
+		{
+			MethodName: "Kick",
+			Handler:    _Comet_Kick_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "connect/connect.proto",
@@ -0,0 +1,34 @@
+package connect
+
+import "encoding/json"
+
+// DiscoveryPrefix comet servers register themselves in etcd with key DiscoveryPrefix+serverID.
+const DiscoveryPrefix = "discovery:"
+
+// Instance is the registration value of a comet server, refreshed with its load.
+type Instance struct {
+	Hostname  string   `json:"hostname"`
+	Region    string   `json:"region"`
+	RPCAddr   string   `json:"rpc_addr"` // grpc地址, job推送消息使用
+	Addrs     []string `json:"addrs"`    // 客户端连接的公网ip
+	Weight    int64    `json:"weight"`
+	Offline   bool     `json:"offline"`
+	ConnCount int32    `json:"conn_count"`
+	IPCount   int32    `json:"ip_count"`
+	Updated   int64    `json:"updated"`
+}
+
+// ParseInstance decode the registration value.
+func ParseInstance(val string) (*Instance, error) {
+	ins := new(Instance)
+	if err := json.Unmarshal([]byte(val), ins); err != nil {
+		return nil, err
+	}
+	return ins, nil
+}
+
+// String encode the registration value.
+func (i *Instance) String() string {
+	b, _ := json.Marshal(i)
+	return string(b)
+}
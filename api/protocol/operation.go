@@ -37,15 +37,50 @@ const (
 	// OpChangeRoomReply change room reply
 	OpChangeRoomReply = int32(13)
 
-	// OpSub subscribe operation
+	// OpSub subscribe operation, body is the op specs like "1000,2000-2999" or "*" for all,
+	// the reply body is the subscribed ones allowed by the server.
 	OpSub = int32(14)
 	// OpSubReply subscribe operation
 	OpSubReply = int32(15)
 
-	// OpUnsub unsubscribe operation
+	// OpUnsub unsubscribe operation, body is the op specs like OpSub
 	OpUnsub = int32(16)
 	// OpUnsubReply unsubscribe operation reply
 	OpUnsubReply = int32(17)
+
+	// OpOfflineMsg offline messages replay after auth
+	OpOfflineMsg = int32(18)
+	// OpOfflineAck ack offline messages
+	OpOfflineAck = int32(19)
+	// OpOfflineAckReply ack offline messages reply
+	OpOfflineAckReply = int32(20)
+
+	// OpMsgAck ack downstream messages, seq is the last received seq
+	OpMsgAck = int32(21)
+	// OpMsgAckReply ack downstream messages reply
+	OpMsgAckReply = int32(22)
+
+	// OpError the request is rejected, body is {"code":...,"message":"..."}.
+	// The conn is closed after it if the protocol version is unsupported or the body is too large.
+	OpError = int32(23)
+
+	// OpReconnect the comet is draining, the client should reconnect to one of the nodes in the body
+	// like {"addrs":["1.1.1.1"]}, the conn is closed after the grace period.
+	OpReconnect = int32(24)
+
+	// OpKicked the conn is kicked by the business side, body is {"code":...,"message":"reason"},
+	// the conn is closed after it.
+	OpKicked = int32(25)
+
+	// OpJoinRoom join the rooms besides the current ones, body is the room ids like "live://1000,group://1"
+	OpJoinRoom = int32(26)
+	// OpJoinRoomReply join rooms reply
+	OpJoinRoomReply = int32(27)
+
+	// OpLeaveRoom leave the rooms, body is the room ids like "live://1000,group://1"
+	OpLeaveRoom = int32(28)
+	// OpLeaveRoomReply leave rooms reply
+	OpLeaveRoomReply = int32(29)
 )
 
 var (
@@ -5,16 +5,13 @@ import (
 	"go-im/internal/connect"
 	"go-im/internal/connect/conf"
 	"go-im/internal/connect/grpc"
-	"go-im/pkg/etcd"
+	"go-im/pkg/metrics"
+	"go-im/pkg/tracing"
 	"os"
 	"os/signal"
 	"syscall"
 )
 
-var (
-	key = "discovery:"
-)
-
 func main() {
 	var (
 		confPath string
@@ -25,15 +22,31 @@ func main() {
 	flag.StringVar(&serverId, "server", "127.0.0.1", "ip")
 	flag.Parse()
 	conf.Parse(confPath)
+	stopTracing, err := tracing.Setup("goim-connect", conf.Conf.Tracing)
+	if err != nil {
+		panic(err)
+	}
+	defer stopTracing()
 	s := connect.NewServer(conf.Conf, serverId)
+	if conf.Conf.Metrics != nil {
+		if err := metrics.Serve(conf.Conf.Metrics.Addr); err != nil {
+			panic(err)
+		}
+	}
 	if err := connect.InitTCP(s, conf.Conf.Tcp.Host); err != nil {
 		panic(err)
 	}
 	if err := connect.InitWebsocket(s, conf.Conf.Websocket.Host); err != nil {
 		panic(err)
 	}
+	if conf.Conf.Admin != nil {
+		if err := connect.InitAdmin(s, conf.Conf.Admin); err != nil {
+			panic(err)
+		}
+	}
 
-	ser, err := etcd.NewRegister([]string{conf.Conf.Discovery.Host}, key+serverId, serverId, int64(conf.Conf.Discovery.Lease))
+	//注册comet的地址和负载, job和logic通过etcd发现
+	ser, err := s.Register(conf.Conf.Discovery)
 	if err != nil {
 		panic(err)
 	}
@@ -44,14 +57,17 @@ func main() {
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, syscall.SIGHUP, syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGINT)
 	for {
-		s := <-c
-		switch s {
+		sig := <-c
+		switch sig {
 		case syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGINT:
+			//撤销注册, 通知客户端迁移, 等待后关闭剩余连接
+			s.Drain()
 			rpcSrv.GracefulStop()
 			ser.Close()
 			return
 		case syscall.SIGHUP:
-
+			//重新加载tls证书, 新连接使用新证书
+			s.ReloadCerts()
 		default:
 			return
 		}
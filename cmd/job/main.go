@@ -4,6 +4,8 @@ import (
 	"flag"
 	"go-im/internal/job"
 	"go-im/internal/job/conf"
+	"go-im/pkg/metrics"
+	"go-im/pkg/tracing"
 	"os"
 	"os/signal"
 	"syscall"
@@ -14,18 +16,29 @@ func main() {
 	flag.StringVar(&confPath, "conf", "configs/connect.yaml", "default config path.")
 	flag.Parse()
 	conf.Parse(confPath)
+	stopTracing, err := tracing.Setup("goim-job", conf.Conf.Tracing)
+	if err != nil {
+		panic(err)
+	}
+	defer stopTracing()
 	s := job.NewServer(conf.Conf)
+	if conf.Conf.Metrics != nil {
+		if err := metrics.Serve(conf.Conf.Metrics.Addr); err != nil {
+			panic(err)
+		}
+	}
 	s.Consume()
 
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, syscall.SIGHUP, syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGINT)
 	for {
-		s := <-c
-		switch s {
+		sig := <-c
+		switch sig {
 		case syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGINT:
 			return
 		case syscall.SIGHUP:
-
+			//重新加载连接comet的客户端证书
+			s.ReloadCerts()
 		default:
 			return
 		}
@@ -6,6 +6,8 @@ import (
 	"go-im/internal/logic/conf"
 	"go-im/internal/logic/grpc"
 	"go-im/internal/logic/http"
+	"go-im/pkg/metrics"
+	"go-im/pkg/tracing"
 	"os"
 	"os/signal"
 	"syscall"
@@ -16,8 +18,18 @@ func main() {
 	flag.StringVar(&confPath, "conf", "configs/connect.yaml", "default config path.")
 	flag.Parse()
 	conf.Parse(confPath)
+	stopTracing, err := tracing.Setup("goim-logic", conf.Conf.Tracing)
+	if err != nil {
+		panic(err)
+	}
+	defer stopTracing()
 	//todo etcd
 	l := logic.New(conf.Conf)
+	if conf.Conf.Metrics != nil {
+		if err := metrics.Serve(conf.Conf.Metrics.Addr); err != nil {
+			panic(err)
+		}
+	}
 	rpcSrv := grpc.New(conf.Conf.RPCServer, l)
 	httpSrv := http.New(conf.Conf.HTTPServer, l)
 	c := make(chan os.Signal, 1)
@@ -0,0 +1,241 @@
+package connect
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"go-im/api/protocol"
+	"go-im/internal/connect/conf"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	adminOK         = 0
+	adminRequestErr = -400
+	adminAuthErr    = -401
+
+	_adminKickReason = "kicked by admin"
+)
+
+// adminResp is the same as the logic http api.
+type adminResp struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+type bucketInfo struct {
+	Bucket   int `json:"bucket"`
+	Channels int `json:"channels"`
+	Rooms    int `json:"rooms"`
+	IPs      int `json:"ips"`
+}
+
+type channelInfo struct {
+	Key       string    `json:"key"`
+	Mid       int64     `json:"mid"`
+	IP        string    `json:"ip"`
+	Room      string    `json:"room"`  //主房间
+	Rooms     []string  `json:"rooms"` //加入的所有房间
+	Watch     string    `json:"watch"` //订阅的op, 如"1000,2000-2999"
+	Connected time.Time `json:"connected"`
+	LastRead  time.Time `json:"last_read"`
+	Queue     int       `json:"queue"` //等待写出的消息数
+	Kicked    bool      `json:"kicked"`
+}
+
+func newChannelInfo(ch *Channel) *channelInfo {
+	info := &channelInfo{
+		Key:       ch.Key,
+		Mid:       ch.Mid,
+		IP:        ch.IP,
+		Rooms:     ch.Rooms(),
+		Watch:     ch.WatchOps(),
+		Connected: ch.created,
+		LastRead:  ch.LastRead(),
+		Queue:     len(ch.signal),
+		Kicked:    ch.Kicked(),
+	}
+	if r := ch.Room(); r != nil {
+		info.Room = r.Id
+	}
+	return info
+}
+
+// InitAdmin serve the admin http api, the requests must carry the token as
+// "Authorization: Bearer <token>". It's not served without a token.
+func InitAdmin(s *Server, c *conf.Admin) error {
+	if c.Token == "" {
+		s.log.Warn("admin token not set, admin api disabled", zap.String("addr", c.Addr))
+		return nil
+	}
+	lis, err := net.Listen("tcp", c.Addr)
+	if err != nil {
+		s.log.Error("listen admin err", zap.String("addr", c.Addr), zap.Error(err))
+		return err
+	}
+	go func() {
+		if err := http.Serve(lis, s.adminHandler(c.Token)); err != nil {
+			s.log.Error("serve admin err", zap.String("addr", c.Addr), zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+func (s *Server) adminHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/admin/buckets", s.adminBuckets)
+	mux.HandleFunc("/admin/channels", s.adminChannels)
+	mux.HandleFunc("/admin/room", s.adminRoom)
+	mux.HandleFunc("/admin/kick", s.adminKick)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") || subtle.ConstantTimeCompare([]byte(auth[len("Bearer "):]), []byte(token)) != 1 {
+			writeAdmin(w, http.StatusUnauthorized, adminResp{Code: adminAuthErr, Message: "unauthorized"})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func writeAdmin(w http.ResponseWriter, status int, resp adminResp) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = jsoniter.NewEncoder(w).Encode(resp)
+}
+
+func adminResult(w http.ResponseWriter, data interface{}) {
+	writeAdmin(w, http.StatusOK, adminResp{Code: adminOK, Data: data})
+}
+
+func adminError(w http.ResponseWriter, msg string) {
+	writeAdmin(w, http.StatusOK, adminResp{Code: adminRequestErr, Message: msg})
+}
+
+// adminBuckets GET /admin/buckets list the buckets with the counts.
+func (s *Server) adminBuckets(w http.ResponseWriter, r *http.Request) {
+	res := make([]*bucketInfo, 0, len(s.buckets))
+	for i, b := range s.buckets {
+		res = append(res, &bucketInfo{
+			Bucket:   i,
+			Channels: b.ChannelCount(),
+			Rooms:    b.RoomCount(),
+			IPs:      len(b.IPCount()),
+		})
+	}
+	adminResult(w, res)
+}
+
+// adminChannels GET /admin/channels?key=xxx or ?mid=123 look up the channels.
+func (s *Server) adminChannels(w http.ResponseWriter, r *http.Request) {
+	chs, err := s.adminLookup(r)
+	if err != nil {
+		adminError(w, err.Error())
+		return
+	}
+	res := make([]*channelInfo, 0, len(chs))
+	for _, ch := range chs {
+		res = append(res, newChannelInfo(ch))
+	}
+	adminResult(w, res)
+}
+
+// adminRoom GET /admin/room?room=live://1000 dump the members of the room in all buckets.
+func (s *Server) adminRoom(w http.ResponseWriter, r *http.Request) {
+	room := r.FormValue("room")
+	if room == "" {
+		adminError(w, "room required")
+		return
+	}
+	res := make([]*channelInfo, 0)
+	for _, ch := range s.roomChannels(room) {
+		res = append(res, newChannelInfo(ch))
+	}
+	adminResult(w, res)
+}
+
+// adminKick POST /admin/kick?key=xxx or ?room=live://1000, the clients are
+// told by the op (OpDisconnectReply by default) with the reason before closed.
+func (s *Server) adminKick(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		adminError(w, "method not allowed")
+		return
+	}
+	op := protocol.OpDisconnectReply
+	if v := r.FormValue("op"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			adminError(w, "bad op")
+			return
+		}
+		op = int32(n)
+	}
+	reason := r.FormValue("reason")
+	if reason == "" {
+		reason = _adminKickReason
+	}
+	var chs []*Channel
+	if room := r.FormValue("room"); room != "" {
+		chs = s.roomChannels(room)
+	} else {
+		var err error
+		if chs, err = s.adminLookup(r); err != nil {
+			adminError(w, err.Error())
+			return
+		}
+	}
+	body := errBody(status.Error(codes.Aborted, reason))
+	var kicked int
+	for _, ch := range chs {
+		if ch.Kick(op, body) {
+			kicked++
+		}
+	}
+	s.log.Info("admin kick", zap.Int32("op", op), zap.String("reason", reason), zap.Int("kicked", kicked))
+	adminResult(w, map[string]int{"kicked": kicked})
+}
+
+// adminLookup find the channels by the key or mid in the request.
+func (s *Server) adminLookup(r *http.Request) ([]*Channel, error) {
+	if key := r.FormValue("key"); key != "" {
+		if ch := s.Bucket(key).Channel(key); ch != nil {
+			return []*Channel{ch}, nil
+		}
+		return nil, nil
+	}
+	v := r.FormValue("mid")
+	if v == "" {
+		return nil, errors.New("key or mid required")
+	}
+	mid, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return nil, errors.New("bad mid")
+	}
+	var chs []*Channel
+	for _, b := range s.buckets {
+		for _, ch := range b.Channels() {
+			if ch.Mid == mid {
+				chs = append(chs, ch)
+			}
+		}
+	}
+	return chs, nil
+}
+
+// roomChannels get the members of the room in all buckets.
+func (s *Server) roomChannels(room string) (chs []*Channel) {
+	for _, b := range s.buckets {
+		if r := b.Room(room); r != nil {
+			chs = append(chs, r.Channels()...)
+		}
+	}
+	return
+}
@@ -0,0 +1,79 @@
+package connect
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"go-im/internal/connect/conf"
+	"go-im/pkg/log"
+	"go.uber.org/zap"
+)
+
+func TestAdmin(t *testing.T) {
+	b := newTestBucket(t)
+	s := &Server{buckets: []*Bucket{b}, log: &log.Log{Logger: zap.NewNop()}}
+	for i, key := range []string{"a", "b"} {
+		ch := NewChannel(&conf.Protocol{})
+		ch.Key, ch.Mid, ch.IP = key, int64(i%2+1), "1.1.1.1"
+		ch.Watch(1000)
+		if err := b.Put("live://1", ch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := s.adminHandler("token")
+	do := func(method, url, token string) (int, adminResp) {
+		req := httptest.NewRequest(method, url, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		var resp adminResp
+		if err := jsoniter.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return w.Code, resp
+	}
+
+	if code, _ := do("GET", "/admin/buckets", "bad"); code != http.StatusUnauthorized {
+		t.Fatalf("bad token code %d", code)
+	}
+	if _, resp := do("GET", "/admin/channels?mid=2", "token"); resp.Code != adminOK || len(resp.Data.([]interface{})) != 1 {
+		t.Fatalf("lookup mid %+v", resp)
+	}
+	_, resp := do("GET", "/admin/room?room=live://1", "token")
+	if members := resp.Data.([]interface{}); len(members) != 2 || members[0].(map[string]interface{})["key"] != "b" {
+		t.Fatalf("room members %+v", resp)
+	}
+	if _, resp = do("GET", "/admin/channels", "token"); resp.Code != adminRequestErr {
+		t.Fatalf("lookup without key %+v", resp)
+	}
+
+	// kick the room, the reason is written before the writer closes the conn
+	if _, resp = do("POST", "/admin/kick?room=live://1&op=1000", "token"); resp.Data.(map[string]interface{})["kicked"] != float64(2) {
+		t.Fatalf("kick %+v", resp)
+	}
+	ch := b.Channel("a")
+	if p, _ := ch.Ready(); p.Op != 1000 {
+		t.Fatalf("kick op %d", p.Op)
+	}
+	if p, _ := ch.Ready(); p != protoClose {
+		t.Fatal("conn not closed after kicked")
+	}
+	if _, resp = do("POST", "/admin/kick?key=a", "token"); resp.Data.(map[string]interface{})["kicked"] != float64(0) {
+		t.Fatalf("kicked twice %+v", resp)
+	}
+}
+
+func TestInitAdminWithoutToken(t *testing.T) {
+	s := &Server{log: &log.Log{Logger: zap.NewNop()}}
+	// not served without a token, the bad addr is never listened
+	if err := InitAdmin(s, &conf.Admin{Addr: "bad addr"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := InitAdmin(s, &conf.Admin{Addr: "bad addr", Token: "token"}); err == nil {
+		t.Fatal("bad addr listened")
+	}
+}
@@ -1,73 +1,95 @@
 package connect
 
 import (
-	"go-im/api/connect"
-	pb "go-im/api/connect"
 	"go-im/api/protocol"
 	"go-im/internal/connect/conf"
+	"go-im/pkg/cityhash"
+	"go-im/pkg/proto"
 	"sync"
-	"sync/atomic"
+	"time"
 )
 
 type Bucket struct {
-	cLock       sync.RWMutex
-	rooms       map[string]*Room
-	chs         map[string]*Channel
-	routines    []chan *connect.BroadcastRoomReq
-	routinesNum uint64
-	ipCnts      map[string]int32
+	c        *conf.Bucket
+	cLock    sync.RWMutex
+	rooms    map[string]*Room
+	chs      map[string]*Channel
+	routines []chan roomFrame
+	ipCnts   map[string]int32
+	windows  map[string]detached //已断开连接的未确认消息, 等待客户端重连后恢复
+	reaper   *Reaper             //关闭心跳超时的连接
+	draining bool                //排空后不再放入新的连接
+}
+
+// detached the window of a closed channel, only resumed by the same mid.
+type detached struct {
+	mid int64
+	w   *window
 }
 
 func NewBucket(bucket *conf.Bucket) (b *Bucket) {
 	b = new(Bucket)
+	b.c = bucket
 	b.ipCnts = make(map[string]int32)
+	b.windows = make(map[string]detached)
+	b.reaper = NewReaper(bucket.ReaperTick, bucket.ReaperSlots, func(ch *Channel) {
+		//关闭连接后读协程退出, 由读协程清理bucket并通知logic断开
+		_ = ch.CloseConn()
+	})
 	b.chs = make(map[string]*Channel, bucket.Channel)
 	b.rooms = make(map[string]*Room, bucket.Room)
-	b.routines = make([]chan *connect.BroadcastRoomReq, bucket.RoutineAmount)
+	b.routines = make([]chan roomFrame, bucket.RoutineAmount)
 	for i := 0; i < bucket.RoutineAmount; i++ {
-		c := make(chan *connect.BroadcastRoomReq, bucket.RoutineSize)
+		c := make(chan roomFrame, bucket.RoutineSize)
 		b.routines[i] = c
 		go b.roomProc(c)
 	}
 	return
 }
 
-func (b *Bucket) Put(roomId string, ch *Channel) error {
-	var (
-		room *Room
-		ok   bool
-		err  error
-	)
-
+// Put put the channel to the bucket, roomId is the primary room if it's not empty.
+func (b *Bucket) Put(roomId string, ch *Channel) (err error) {
 	b.cLock.Lock()
 	defer b.cLock.Unlock()
+	if b.draining {
+		return errDraining
+	}
 	//close old channel
+	//key由客户端提供, 只有同一个mid才能恢复旧连接未确认的消息
 	if oldCh := b.chs[ch.Key]; oldCh != nil {
+		if oldCh.Mid == ch.Mid {
+			ch.resume(oldCh.win())
+		}
 		oldCh.Close()
+	} else if d, ok := b.windows[ch.Key]; ok {
+		//断线重连, 恢复旧连接未确认的消息
+		delete(b.windows, ch.Key)
+		if d.mid == ch.Mid && d.w.alive(time.Now()) {
+			ch.resume(d.w)
+		}
 	}
 	b.chs[ch.Key] = ch
-	if roomId != "" {
-		//房间不存在则创建新房间
-		if room, ok = b.rooms[roomId]; !ok {
-			room = NewRoom(roomId)
-			b.rooms[roomId] = room
-		}
-		ch.Room = room
+	if ch.hb > 0 {
+		b.reaper.Add(ch)
 	}
 	b.ipCnts[ch.IP]++
-	if room != nil {
-		err = room.Put(ch)
+	if roomId != "" {
+		ch.setRoom(roomId)
+		err = b.joinRoom(b.roomOrNew(roomId), ch)
 	}
-	return err
+	return
 }
 
 //Del 删除bucket和room的channel的信息
 func (b *Bucket) Del(ch *Channel) {
-	room := ch.Room
+	b.reaper.Del(ch)
 	b.cLock.Lock()
 	if oldCh, ok := b.chs[ch.Key]; ok {
 		if oldCh == ch {
-			delete(b.rooms, ch.Key)
+			delete(b.chs, ch.Key)
+			if w := ch.win(); w.detach(time.Now()) {
+				b.windows[ch.Key] = detached{mid: ch.Mid, w: w}
+			}
 		}
 
 		//ip记录
@@ -78,11 +100,8 @@ func (b *Bucket) Del(ch *Channel) {
 		}
 	}
 	b.cLock.Unlock()
-	if room != nil && room.Del(ch) {
-		// if empty room, must delete from bucket
-		b.DelRoom(room)
-	}
-	return
+	//离开加入的所有房间
+	b.LeaveRoom(ch, ch.Rooms()...)
 }
 
 // DelRoom delete a room by roomid.
@@ -93,49 +112,174 @@ func (b *Bucket) DelRoom(room *Room) {
 	room.Close()
 }
 
+// ChangeRoom change the primary room, the other joined rooms are kept. The old
+// one is left only after the new one is joined, the channel is kept as it was
+// if the join fails.
 func (b *Bucket) ChangeRoom(roomId string, ch *Channel) error {
-	var (
-		oldRoom = ch.Room
-		room    *Room
-		ok      bool
-	)
-	//离开房间,则roomId为空
-	if roomId == "" {
-		//最后一个离开房间的
-		if oldRoom != nil && oldRoom.Del(ch) {
-			b.DelRoom(oldRoom)
+	var old string
+	if room := ch.Room(); room != nil {
+		old = room.Id
+	}
+	if roomId != old {
+		//旧房间随后离开, 不计入房间数的限制
+		spare := 0
+		if old != "" {
+			spare = 1
 		}
-		//当前所在的房间也为空
-		ch.Room = nil
+		//离开房间,则roomId为空
+		if err := b.join(ch, roomId, spare); err != nil {
+			return err
+		}
+	}
+	ch.setRoom(roomId)
+	if old != "" && old != roomId {
+		b.LeaveRoom(ch, old)
+	}
+	return nil
+}
+
+// JoinRoom join the channel to the rooms, the joined ones are skipped.
+func (b *Bucket) JoinRoom(ch *Channel, roomIds ...string) error {
+	for _, roomId := range roomIds {
+		if err := b.join(ch, roomId, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// join join the channel to a room, spare is the number of the joined rooms
+// going to be left which are not counted in MaxRooms.
+func (b *Bucket) join(ch *Channel, roomId string, spare int) error {
+	if roomId == "" || ch.InRoom(roomId) {
 		return nil
 	}
+	if b.c.MaxRooms > 0 && ch.RoomCount()-spare >= b.c.MaxRooms {
+		return errTooManyRooms
+	}
 	b.cLock.Lock()
-	//判断房间是否已经创建
-	if room, ok = b.rooms[roomId]; !ok {
+	room := b.roomOrNew(roomId)
+	b.cLock.Unlock()
+	return b.joinRoom(room, ch)
+}
+
+// LeaveRoom remove the channel from the rooms, the empty rooms are deleted.
+func (b *Bucket) LeaveRoom(ch *Channel, roomIds ...string) {
+	for _, roomId := range roomIds {
+		n := ch.delRoom(roomId)
+		if n != nil && n.room.Del(n) {
+			// if empty room, must delete from bucket
+			b.DelRoom(n.room)
+		}
+	}
+}
+
+// roomOrNew get the room, create it if not exists, b.cLock must be held.
+func (b *Bucket) roomOrNew(roomId string) *Room {
+	room, ok := b.rooms[roomId]
+	if !ok {
 		room = NewRoom(roomId)
 		b.rooms[roomId] = room
 	}
-	b.cLock.Unlock()
-	if oldRoom != nil && oldRoom.Del(ch) {
-		b.DelRoom(oldRoom)
-	}
-	if err := room.Put(ch); err != nil {
+	return room
+}
+
+func (b *Bucket) joinRoom(room *Room, ch *Channel) error {
+	n := &roomNode{ch: ch, room: room}
+	if err := room.Put(n); err != nil {
 		return err
 	}
-	ch.Room = room
+	ch.addRoom(n)
 	return nil
 }
 
-// roomproc
-func (b *Bucket) roomProc(c chan *connect.BroadcastRoomReq) {
+// roomproc 合并房间消息, 达到RoomBatch条或者每RoomSignal推送一次
+func (b *Bucket) roomProc(c chan roomFrame) {
+	if b.c.RoomBatch <= 1 || b.c.RoomSignal <= 0 {
+		for arg := range c {
+			b.pushRoom(arg.room, arg.f)
+			arg.f.Release()
+		}
+		return
+	}
+	var (
+		batches = make(map[string]*roomBatch)
+		ticker  = time.NewTicker(b.c.RoomSignal)
+	)
+	defer ticker.Stop()
 	for {
-		arg := <-c
-		if room := b.Room(arg.RoomID); room != nil {
-			room.Push(arg.Proto)
+		select {
+		case arg := <-c:
+			batch, ok := batches[arg.room]
+			if !ok {
+				batch = new(roomBatch)
+				batches[arg.room] = batch
+			}
+			batch.add(arg.f)
+			if batch.n >= b.c.RoomBatch {
+				b.flushRoom(arg.room, batch)
+			}
+		case <-ticker.C:
+			for room, batch := range batches {
+				if batch.n == 0 {
+					//空闲的房间不再保留
+					delete(batches, room)
+					continue
+				}
+				b.flushRoom(room, batch)
+			}
 		}
 	}
 }
 
+// roomBatch is the messages of a room waiting to be merged.
+type roomBatch struct {
+	first *Frame // 只有一条消息时直接推送, 不合并
+	ver   int32  // 合并后的消息沿用房间消息的协议版本
+	body  []byte
+	n     int
+}
+
+func (rb *roomBatch) add(f *Frame) {
+	if rb.n == 0 {
+		rb.first = f
+		rb.ver = proto.BaseVer(f.p.Ver)
+	} else {
+		if rb.n == 1 {
+			rb.body = proto.AppendPackage(rb.body, rb.first.plainProto())
+			rb.first.Release()
+			rb.first = nil
+		}
+		rb.body = proto.AppendPackage(rb.body, f.plainProto())
+		f.Release()
+	}
+	rb.n++
+}
+
+func (b *Bucket) flushRoom(room string, rb *roomBatch) {
+	switch rb.n {
+	case 0:
+		return
+	case 1:
+		b.pushRoom(room, rb.first)
+		rb.first.Release()
+		rb.first = nil
+	default:
+		//Frame引用body直到所有连接写完, 每次合并使用新的body
+		f := NewFrame(&protocol.Proto{Ver: rb.ver, Op: protocol.OpRaw, Body: rb.body})
+		b.pushRoom(room, f)
+		f.Release()
+		rb.body = nil
+	}
+	rb.n = 0
+}
+
+func (b *Bucket) pushRoom(roomID string, f *Frame) {
+	if room := b.Room(roomID); room != nil {
+		room.Push(f)
+	}
+}
+
 func (b *Bucket) Room(roomId string) *Room {
 	b.cLock.RLock()
 	defer b.cLock.RUnlock()
@@ -182,21 +326,30 @@ func (b *Bucket) Rooms() (res map[string]struct{}) {
 	return
 }
 
-// BroadcastRoom broadcast a message to specified room
-func (b *Bucket) BroadcastRoom(req *pb.BroadcastRoomReq) {
-	num := atomic.AddUint64(&b.routinesNum, 1) % uint64(len(b.routines))
-	b.routines[num] <- req
+// roomFrame is a frame to broadcast to the room.
+type roomFrame struct {
+	room string
+	f    *Frame
+}
+
+// BroadcastRoom broadcast a message to specified room, the frame is retained
+// until pushed. The messages of a room are handled by the same routine to
+// keep their order.
+func (b *Bucket) BroadcastRoom(roomID string, f *Frame) {
+	f.Retain()
+	num := cityhash.CityHash32([]byte(roomID), uint32(len(roomID))) % uint32(len(b.routines))
+	b.routines[num] <- roomFrame{room: roomID, f: f}
 }
 
 // Broadcast push msgs to all channels in the bucket.
-func (b *Bucket) Broadcast(p *protocol.Proto, op int32) {
+func (b *Bucket) Broadcast(f *Frame, op int32) {
 	var ch *Channel
 	b.cLock.RLock()
 	for _, ch = range b.chs {
 		if !ch.NeedPush(op) {
 			continue
 		}
-		_ = ch.Push(p)
+		_ = ch.PushFrame(f)
 	}
 	b.cLock.RUnlock()
 }
@@ -205,10 +358,45 @@ func (b *Bucket) Broadcast(p *protocol.Proto, op int32) {
 func (c *Channel) NeedPush(op int32) bool {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
-	if _, ok := c.watchOps[op]; ok {
-		return true
+	return c.watchOps.Has(op)
+}
+
+// Channels get all channels in the bucket.
+func (b *Bucket) Channels() []*Channel {
+	b.cLock.RLock()
+	chs := make([]*Channel, 0, len(b.chs))
+	for _, ch := range b.chs {
+		chs = append(chs, ch)
+	}
+	b.cLock.RUnlock()
+	return chs
+}
+
+// Drain stop putting the channels into the bucket, and get the channels in it.
+// The channels put before are all returned, so none is missed by the drain.
+func (b *Bucket) Drain() []*Channel {
+	b.cLock.Lock()
+	b.draining = true
+	chs := make([]*Channel, 0, len(b.chs))
+	for _, ch := range b.chs {
+		chs = append(chs, ch)
 	}
-	return false
+	b.cLock.Unlock()
+	return chs
+}
+
+// Resend resend the timeout messages of all channels, and drop the windows not resumed in time.
+func (b *Bucket) Resend(now time.Time) {
+	for _, ch := range b.Channels() {
+		ch.resend(now, false)
+	}
+	b.cLock.Lock()
+	for key, d := range b.windows {
+		if !d.w.alive(now) {
+			delete(b.windows, key)
+		}
+	}
+	b.cLock.Unlock()
 }
 
 // ChannelCount channel count in the bucket
@@ -221,6 +409,25 @@ func (b *Bucket) RoomCount() int {
 	return len(b.rooms)
 }
 
+// IPCount get the distinct ips in the bucket.
+func (b *Bucket) IPCount() (res map[string]struct{}) {
+	b.cLock.RLock()
+	res = make(map[string]struct{}, len(b.ipCnts))
+	for ip := range b.ipCnts {
+		res[ip] = struct{}{}
+	}
+	b.cLock.RUnlock()
+	return
+}
+
+// IPConns get the connections of the ip in the bucket.
+func (b *Bucket) IPConns(ip string) (n int32) {
+	b.cLock.RLock()
+	n = b.ipCnts[ip]
+	b.cLock.RUnlock()
+	return
+}
+
 // Channel get a channel by sub key.
 func (b *Bucket) Channel(key string) (ch *Channel) {
 	b.cLock.RLock()
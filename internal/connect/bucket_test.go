@@ -0,0 +1,131 @@
+package connect
+
+import (
+	"testing"
+	"time"
+
+	"go-im/api/protocol"
+	"go-im/internal/connect/conf"
+	"go-im/pkg/proto"
+)
+
+// newTestBucket new a small bucket with one room routine for the tests.
+func newTestBucket(t *testing.T) *Bucket {
+	t.Helper()
+	return NewBucket(&conf.Bucket{Channel: 8, Room: 8, RoutineAmount: 1, RoutineSize: 1, ReaperTick: time.Second, ReaperSlots: 4})
+}
+
+func TestBucketRoomBatch(t *testing.T) {
+	b := NewBucket(&conf.Bucket{
+		Channel:       8,
+		Room:          8,
+		RoutineAmount: 2,
+		RoutineSize:   16,
+		ReaperTick:    time.Second,
+		ReaperSlots:   4,
+		RoomBatch:     3,
+		RoomSignal:    50 * time.Millisecond,
+	})
+	ch := NewChannel(&conf.Protocol{})
+	ch.Key = "key"
+	if err := b.Put("live://1000", ch); err != nil {
+		t.Fatal(err)
+	}
+	push := func(op int32, body string) {
+		f := NewFrame(&protocol.Proto{Ver: 1, Op: op, Body: []byte(body)})
+		b.BroadcastRoom("live://1000", f)
+		f.Release()
+	}
+	ready := func() *protocol.Proto {
+		select {
+		case s := <-ch.signal:
+			s.f.Release()
+			return s.p
+		case <-time.After(time.Second):
+			t.Fatal("no message pushed")
+		}
+		return nil
+	}
+
+	// merged once the batch is full
+	push(1000, "a")
+	push(1001, "b")
+	push(1002, "c")
+	p := ready()
+	if p.Op != protocol.OpRaw {
+		t.Fatalf("want batch got op %d", p.Op)
+	}
+	ps, err := proto.SplitBatch(p.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ps) != 3 || ps[0].Op != 1000 || string(ps[2].Body) != "c" || p.Ver != 1 {
+		t.Fatalf("wrong batch %v", ps)
+	}
+
+	// the next batch doesn't overwrite the pushed one
+	first := string(p.Body)
+	push(1004, "e")
+	push(1005, "f")
+	push(1006, "g")
+	if p2 := ready(); string(p.Body) != first || p2.Op != protocol.OpRaw {
+		t.Fatalf("batch overwritten %q", p.Body)
+	}
+
+	// a single message is pushed as is after the signal
+	push(1003, "d")
+	if p = ready(); p.Op != 1003 || string(p.Body) != "d" {
+		t.Fatalf("wrong single message %v", p)
+	}
+}
+
+func TestBucketMultiRoom(t *testing.T) {
+	b := newTestBucket(t)
+	b.c.MaxRooms = 3
+	a, c := NewChannel(&conf.Protocol{}), NewChannel(&conf.Protocol{})
+	a.Key, c.Key = "a", "c"
+	if err := b.Put("live://1", a); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Put("live://1", c); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.JoinRoom(a, "group://1", "group://2", "live://1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.JoinRoom(a, "group://3"); err != errTooManyRooms {
+		t.Fatalf("join over max rooms err %v", err)
+	}
+	if rooms := a.Rooms(); len(rooms) != 3 || rooms[0] != "group://1" {
+		t.Fatalf("rooms %v", rooms)
+	}
+	if r := b.Room("live://1"); r.Online != 2 || len(r.Channels()) != 2 {
+		t.Fatalf("live room online %d", r.Online)
+	}
+
+	// leaving a room keeps the others, the empty room is deleted
+	b.LeaveRoom(a, "group://1")
+	if b.Room("group://1") != nil || a.InRoom("group://1") || !a.InRoom("group://2") {
+		t.Fatalf("leave room rooms %v", a.Rooms())
+	}
+
+	// the primary room is moved, the joined ones are kept
+	if err := b.ChangeRoom("live://2", a); err != nil {
+		t.Fatal(err)
+	}
+	if a.Room().Id != "live://2" || a.InRoom("live://1") || !a.InRoom("group://2") {
+		t.Fatalf("change room rooms %v", a.Rooms())
+	}
+	if b.Room("live://1").Online != 1 {
+		t.Fatal("not left the old primary room")
+	}
+
+	// deleted from all the rooms
+	b.Del(a)
+	if b.Room("live://2") != nil || b.Room("group://2") != nil || a.RoomCount() != 0 {
+		t.Fatalf("rooms left %v", b.Rooms())
+	}
+	if r := b.Room("live://1"); r == nil || r.Online != 1 {
+		t.Fatal("other channel left")
+	}
+}
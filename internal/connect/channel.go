@@ -4,59 +4,334 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/pkg/errors"
 	"go-im/api/protocol"
+	"go-im/internal/connect/conf"
+	"go-im/pkg/opset"
 	"net"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// policy limits what the client may subscribe or join after auth, given by logic.
+type policy struct {
+	ops   opset.Set           //允许订阅的op, nil不限制
+	rooms map[string]struct{} //允许加入的房间, nil不限制
+}
+
+func newPolicy(ops, rooms []string) (p policy, err error) {
+	if p.ops, err = opset.Parse(ops...); err != nil {
+		return
+	}
+	if len(rooms) > 0 {
+		p.rooms = make(map[string]struct{}, len(rooms))
+		for _, room := range rooms {
+			p.rooms[room] = struct{}{}
+		}
+	}
+	return
+}
+
+// allowRoom reports whether the client may join the room.
+func (p *policy) allowRoom(room string) bool {
+	if p.rooms == nil {
+		return true
+	}
+	_, ok := p.rooms[room]
+	return ok
+}
+
+// allowRooms reports whether the client may join all the rooms.
+func (p *policy) allowRooms(rooms []string) bool {
+	for _, room := range rooms {
+		if !p.allowRoom(room) {
+			return false
+		}
+	}
+	return true
+}
+
 type Channel struct {
-	Room     *Room
-	Next     *Channel
-	Prev     *Channel
-	signal   chan *protocol.Proto
+	rooms    map[string]*roomNode //加入的所有房间, 由mutex保护
+	room     string               //认证或切换的主房间, 心跳返回它的在线人数
+	signal   chan signal
 	Mid      int64  //memberID
 	Key      string //相等于sessionId
 	IP       string
-	watchOps map[int32]struct{} //int32 是房间号 map 多个房间号 一个 goim 终端能够接收多个房间发送来的 im 消息
+	watchOps opset.Set //订阅的消息op, 房间见rooms
+	policy   policy    //认证时logic下发的订阅和加入房间的限制
 	mutex    sync.RWMutex
 	ws       *websocket.Conn
-	connTcp  *net.TCPConn
+	wsBinary bool          //websocket使用二进制帧, 格式同tcp
+	connTcp  net.Conn      //tcp或tls连接
+	window   *window       //下行消息的重传窗口
+	resumed  bool          //继承了旧连接的窗口, 等待客户端上报最后收到的seq
+	hb       time.Duration //心跳超时时间, 超时未读到数据则关闭连接
+	lastRead int64         //最后一次读到数据的时间 unix nano
+	ver      *version      //握手或认证时协商的协议版本
+	drained  int32         //排空时关闭, 已批量通知logic断开
+	kicked   int32         //超过连接数限制被踢, 等待下发通知后关闭
+	created  time.Time     //连接建立的时间
+	limiter  *limiter      //上行消息的速率限制, 只在读协程使用
 }
 
 // NewChannel new a channel.
-func NewChannel(cli, svr int) *Channel {
-	c := new(Channel)
-	//c.CliProto.Init(cli)
-	c.signal = make(chan *protocol.Proto, 1024)
-	c.watchOps = make(map[int32]struct{})
-	return c
+func NewChannel(c *conf.Protocol) *Channel {
+	ch := new(Channel)
+	ch.signal = make(chan signal, 1024)
+	ch.rooms = make(map[string]*roomNode)
+	ch.window = newWindow(c.AckWindow, c.AckTimeout, c.ResumeTimeout)
+	ch.created = time.Now()
+	ch.limiter = newLimiter(c.RateLimit)
+	return ch
 }
 
+// Watch watch the ops, they are not checked by the policy.
 func (c *Channel) Watch(accepts ...int32) {
 	c.mutex.Lock()
-	for _, op := range accepts {
-		c.watchOps[op] = struct{}{}
+	c.watchOps.Union(opset.Of(accepts...))
+	c.mutex.Unlock()
+}
+
+// Subscribe watch the ops allowed by the policy, the subscribed ones are returned.
+func (c *Channel) Subscribe(ops opset.Set) opset.Set {
+	if c.policy.ops != nil {
+		ops = ops.Intersect(c.policy.ops)
 	}
+	c.mutex.Lock()
+	c.watchOps.Union(ops)
+	c.mutex.Unlock()
+	return ops
+}
+
+// Unwatch stop watching the ops.
+func (c *Channel) Unwatch(ops opset.Set) {
+	c.mutex.Lock()
+	c.watchOps.Sub(ops)
 	c.mutex.Unlock()
 }
 
 //Close 发送关闭信号 关闭这个channel
 func (c *Channel) Close() {
-	c.signal <- protocol.ProtoFinish
+	c.signal <- signal{p: protocol.ProtoFinish}
+}
+
+// WatchOps get the watched ops like "1000,2000-2999".
+func (c *Channel) WatchOps() string {
+	c.mutex.RLock()
+	ops := c.watchOps.String()
+	c.mutex.RUnlock()
+	return ops
+}
+
+// Room get the primary room, nil if not in any room.
+func (c *Channel) Room() (room *Room) {
+	c.mutex.RLock()
+	if n := c.rooms[c.room]; n != nil {
+		room = n.room
+	}
+	c.mutex.RUnlock()
+	return
+}
+
+// Rooms get the ids of the joined rooms in order.
+func (c *Channel) Rooms() []string {
+	c.mutex.RLock()
+	ids := make([]string, 0, len(c.rooms))
+	for id := range c.rooms {
+		ids = append(ids, id)
+	}
+	c.mutex.RUnlock()
+	sort.Strings(ids)
+	return ids
+}
+
+// InRoom reports whether the channel joined the room.
+func (c *Channel) InRoom(id string) bool {
+	c.mutex.RLock()
+	_, ok := c.rooms[id]
+	c.mutex.RUnlock()
+	return ok
+}
+
+// RoomCount the number of the joined rooms.
+func (c *Channel) RoomCount() int {
+	c.mutex.RLock()
+	n := len(c.rooms)
+	c.mutex.RUnlock()
+	return n
+}
+
+func (c *Channel) addRoom(n *roomNode) {
+	c.mutex.Lock()
+	c.rooms[n.room.Id] = n
+	c.mutex.Unlock()
+}
+
+func (c *Channel) delRoom(id string) (n *roomNode) {
+	c.mutex.Lock()
+	if n = c.rooms[id]; n != nil {
+		delete(c.rooms, id)
+	}
+	c.mutex.Unlock()
+	return
+}
+
+// setRoom set the primary room, the previous one is returned.
+func (c *Channel) setRoom(id string) (old string) {
+	c.mutex.Lock()
+	old, c.room = c.room, id
+	c.mutex.Unlock()
+	return
+}
+
+// Touch record the time of reading data from client.
+func (c *Channel) Touch() {
+	atomic.StoreInt64(&c.lastRead, time.Now().UnixNano())
+}
+
+// LastRead the last time of reading data from client.
+func (c *Channel) LastRead() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&c.lastRead))
+}
+
+// CloseConn close the underlying connection, the blocked reader will exit and clean up the channel.
+func (c *Channel) CloseConn() error {
+	if c.ws != nil {
+		return c.ws.Close()
+	}
+	if c.connTcp != nil {
+		return c.connTcp.Close()
+	}
+	return nil
+}
+
+// protoClose tell the writer to close the connection, the messages queued
+// before it are written first.
+var protoClose = &protocol.Proto{Op: protocol.OpProtoFinish}
+
+// Kick tell the client why it's kicked by the op, the writer closes the
+// connection after it's written. It returns false if already kicked.
+func (c *Channel) Kick(op int32, body []byte) bool {
+	if !atomic.CompareAndSwapInt32(&c.kicked, 0, 1) {
+		return false
+	}
+	c.ReplyClose(&protocol.Proto{Ver: 1, Op: op, Body: body})
+	return true
+}
+
+// ReplyClose reply the client why it's closed, the writer closes the
+// connection after the reply is written.
+func (c *Channel) ReplyClose(p *protocol.Proto) {
+	if c.Reply(p) != nil || c.send(protoClose, nil) != nil {
+		//队列已满, 直接关闭
+		_ = c.CloseConn()
+	}
+}
+
+// Kicked reports whether the channel is kicked.
+func (c *Channel) Kicked() bool {
+	return atomic.LoadInt32(&c.kicked) == 1
+}
+
+// signal is a message to write, f is set if it's a shared broadcast frame
+// which must be released after written.
+type signal struct {
+	p *protocol.Proto
+	f *Frame
 }
 
 // Signal send signal to the channel, protocol ready.
 func (c *Channel) Signal() {
-	c.signal <- protocol.ProtoReady
+	c.signal <- signal{p: protocol.ProtoReady}
 }
-func (c *Channel) Ready() *protocol.Proto {
-	return <-c.signal
+
+// Ready get the next message to write, the frame must be released after written.
+func (c *Channel) Ready() (*protocol.Proto, *Frame) {
+	s := <-c.signal
+	return s.p, s.f
 }
 
+// Push push a downstream message, the message is assigned a seq of this channel
+// and kept in the window until the client acks it.
 func (c *Channel) Push(p *protocol.Proto) (err error) {
+	return c.push(p, nil)
+}
+
+// PushFrame push a broadcast frame, the channel writes the pre-encoded bytes
+// with its own seq.
+func (c *Channel) PushFrame(f *Frame) (err error) {
+	return c.push(f.p, f)
+}
+
+func (c *Channel) push(p *protocol.Proto, f *Frame) (err error) {
+	w := c.win()
+	p = w.add(p, f)
+	f.Retain()
+	if err = c.send(p, f); err != nil && w.size > 0 {
+		//已经在窗口中, 超时后会重发
+		err = nil
+	}
+	return
+}
+
+// Reply reply the client's request, the message is not sequenced.
+func (c *Channel) Reply(p *protocol.Proto) (err error) {
+	return c.send(p, nil)
+}
+
+// send the message to the writer, the reference of the frame is taken over,
+// and released if failed.
+func (c *Channel) send(p *protocol.Proto, f *Frame) (err error) {
 	select {
-	case c.signal <- p:
+	case c.signal <- signal{p: p, f: f}:
 	default:
+		f.Release()
+		signalDrops.Inc()
 		err = errors.New("signal channel not enough")
 	}
 	return
 }
+
+// Ack the client acks all downstream messages whose seq <= seq.
+// If the channel resumed an old window, the rest messages are resent at once.
+func (c *Channel) Ack(seq int32) {
+	c.win().ack(seq)
+	c.mutex.Lock()
+	resumed := c.resumed
+	c.resumed = false
+	c.mutex.Unlock()
+	if resumed {
+		c.resend(time.Now(), true)
+	}
+}
+
+// win get the window, it's replaced when resumed.
+func (c *Channel) win() *window {
+	c.mutex.RLock()
+	w := c.window
+	c.mutex.RUnlock()
+	return w
+}
+
+// resume take over the window of the old channel with the same key.
+func (c *Channel) resume(w *window) {
+	c.mutex.Lock()
+	c.window = w
+	c.resumed = true
+	c.mutex.Unlock()
+}
+
+// resend resend the timeout messages in window.
+func (c *Channel) resend(now time.Time, force bool) {
+	ps := c.win().expired(now, force)
+	for i, pd := range ps {
+		if c.send(pd.p, pd.f) != nil {
+			//channel已满, 释放剩余的引用
+			for _, rest := range ps[i+1:] {
+				rest.f.Release()
+			}
+			return
+		}
+	}
+}
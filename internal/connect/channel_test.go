@@ -0,0 +1,92 @@
+package connect
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+
+	"go-im/api/protocol"
+	"go-im/internal/connect/conf"
+	"go-im/pkg/opset"
+	"go-im/pkg/proto"
+)
+
+func TestNeedPush(t *testing.T) {
+	ch := NewChannel(&conf.Protocol{})
+	ch.Watch(protocol.OpOfflineMsg, 1000)
+	ch.policy.ops, _ = opset.Parse("1000-2999")
+	s := &Server{}
+	operate := func(op int32, body string) *protocol.Proto {
+		p := &protocol.Proto{Ver: 1, Op: op, Body: []byte(body)}
+		if err := s.Operate(context.Background(), p, nil, ch); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	check := func(want map[int32]bool) {
+		t.Helper()
+		for op, need := range want {
+			if ch.NeedPush(op) != need {
+				t.Fatalf("NeedPush(%d) want %v watch %s", op, need, ch.WatchOps())
+			}
+		}
+	}
+
+	// the ops out of the policy are dropped, the ops watched at auth are kept
+	if p := operate(protocol.OpSub, "2000-3999,5000"); p.Op != protocol.OpSubReply || string(p.Body) != "2000-2999" {
+		t.Fatalf("sub reply %d %s", p.Op, p.Body)
+	}
+	check(map[int32]bool{protocol.OpOfflineMsg: true, 1000: true, 1001: false, 2000: true, 2999: true, 3000: false, 5000: false})
+
+	// unsubscribe a part of the range
+	if p := operate(protocol.OpUnsub, "1000,2500-2599"); p.Op != protocol.OpUnsubReply {
+		t.Fatalf("unsub reply %d", p.Op)
+	}
+	check(map[int32]bool{1000: false, 2499: true, 2500: false, 2599: false, 2600: true})
+	if got := ch.WatchOps(); got != "18,2000-2499,2600-2999" {
+		t.Fatalf("watch %s", got)
+	}
+
+	// the wildcard subscribes all the allowed ops
+	operate(protocol.OpSub, "*")
+	check(map[int32]bool{1000: true, 2550: true, 3000: false})
+	operate(protocol.OpUnsub, "*")
+	check(map[int32]bool{protocol.OpOfflineMsg: false, 1000: false, 2000: false})
+
+	// not limited without the policy
+	ch.policy.ops = nil
+	operate(protocol.OpSub, "*")
+	check(map[int32]bool{0: true, 5000: true})
+
+	for _, op := range []int32{protocol.OpSub, protocol.OpUnsub} {
+		if p := operate(op, "1000-"); p.Op != protocol.OpError {
+			t.Fatalf("bad spec reply op %d %s", p.Op, p.Body)
+		}
+	}
+}
+
+func TestReplyCloseTCP(t *testing.T) {
+	conn, client := net.Pipe()
+	ch := NewChannel(&conf.Protocol{})
+	ch.connTcp = conn
+	s := &Server{}
+	done := make(chan struct{})
+	go func() {
+		s.writeTCPData(context.Background(), ch)
+		close(done)
+	}()
+	ch.ReplyClose(&protocol.Proto{Ver: 1, Op: protocol.OpDisconnectReply, Body: []byte("bye")})
+
+	// the reply is written before the conn is closed
+	rd := bufio.NewReader(client)
+	p := new(protocol.Proto)
+	if err := proto.ReadTcp(p, rd); err != nil || p.Op != protocol.OpDisconnectReply || string(p.Body) != "bye" {
+		t.Fatalf("read reply op %d err %v", p.Op, err)
+	}
+	if err := proto.ReadTcp(p, rd); err == nil {
+		t.Fatal("conn not closed after reply")
+	}
+	ch.Close()
+	<-done
+}
@@ -0,0 +1,72 @@
+package connect
+
+import (
+	"go-im/api/protocol"
+	"go-im/pkg/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errBadCompress the upstream body can't be decompressed.
+var errBadCompress = status.Error(codes.InvalidArgument, "bad compressed body")
+
+// codec get the downstream compression of the channel, the json websocket
+// relies on permessage-deflate, so its bodies are never compressed.
+func (c *Channel) codec() (codec proto.Codec, threshold int) {
+	if c.ver == nil || (c.ws != nil && !c.wsBinary) {
+		return proto.CodecNone, 0
+	}
+	return c.ver.codec, int(c.ver.CompressThreshold)
+}
+
+// decompressBody decompress the upstream body if the client compressed it,
+// the decompressed body is also limited by the version.
+func decompressBody(ch *Channel, p *protocol.Proto) (err error) {
+	codec := proto.CodecOf(p.Ver)
+	if codec == proto.CodecNone {
+		return
+	}
+	if p.Body, err = proto.Decompress(codec, p.Body, int(ch.ver.MaxBodySize)); err != nil && err != proto.ErrBodyLen {
+		err = errBadCompress
+	}
+	p.Ver = proto.BaseVer(p.Ver)
+	return
+}
+
+// encodeBody get the body in the codec, body is in the codec src.
+// The original body is returned if it's not worth compressing or failed.
+func encodeBody(body []byte, src, dst proto.Codec, threshold int) ([]byte, proto.Codec) {
+	if src == dst {
+		return body, src
+	}
+	if src != proto.CodecNone {
+		plain, err := proto.Decompress(src, body, proto.MaxDecompressSize)
+		if err != nil {
+			//无法解压则原样下发
+			return body, src
+		}
+		body = plain
+	}
+	if dst == proto.CodecNone || len(body) < threshold {
+		return body, proto.CodecNone
+	}
+	if b, err := proto.Compress(dst, body); err == nil && len(b) < len(body) {
+		return b, dst
+	}
+	return body, proto.CodecNone
+}
+
+// compressProto get the proto to write in the codec of the channel, p is
+// shared by the window and never modified.
+func compressProto(ch *Channel, p *protocol.Proto) *protocol.Proto {
+	codec, threshold := ch.codec()
+	src := proto.CodecOf(p.Ver)
+	if src == codec {
+		return p
+	}
+	body, dst := encodeBody(p.Body, src, codec, threshold)
+	if dst == src {
+		return p
+	}
+	return &protocol.Proto{Ver: proto.WithCodec(p.Ver, dst), Op: p.Op, Seq: p.Seq, Body: body}
+}
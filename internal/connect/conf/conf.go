@@ -2,6 +2,7 @@ package conf
 
 import (
 	"github.com/spf13/viper"
+	"go-im/pkg/tracing"
 	"strings"
 	"time"
 )
@@ -42,13 +43,39 @@ func Parse(path string) {
 }
 
 type Config struct {
+	Env       *Env
+	Drain     *Drain
+	Limit     *Limit
 	Discovery *Discovery
 	Bucket    *Bucket
 	Tcp       *TCP
 	Mode      *Mode
 	Protocol  *Protocol
 	RPCServer *RPCServer
+	RPCClient *RPCClient
 	Websocket *Websocket
+	Metrics   *Metrics
+	Tracing   *tracing.Config
+	Admin     *Admin
+}
+
+// Metrics is the prometheus metrics endpoint config.
+type Metrics struct {
+	Addr string
+}
+
+// Admin is the admin http api config.
+type Admin struct {
+	Addr  string
+	Token string //请求头Authorization: Bearer <token>
+}
+
+// Env is env config, registered to discovery for logic to dispatch clients.
+type Env struct {
+	Region  string
+	Weight  int64
+	Addrs   []string //客户端连接的公网ip, 为空时使用server id
+	Offline bool     //不再分配新的客户端
 }
 
 type Discovery struct {
@@ -57,19 +84,37 @@ type Discovery struct {
 	Lease  int
 }
 
+// Drain is the graceful shutdown config.
+type Drain struct {
+	Grace time.Duration //通知客户端重连后等待的时间, 之后关闭剩余的连接
+	Hints int           //下发给客户端的备选节点数量
+}
+
+// Limit is connection limit config.
+type Limit struct {
+	MaxIPConns int    //每个ip在本节点的最大连接数, 0不限制
+	IPPolicy   string //reject or kick, 超过限制时拒绝新连接或踢掉最早的
+}
+
 type Websocket struct {
 	Host        []string
 	TlsOpen     bool
+	TlsBind     []string //wss监听的地址
 	CertFile    string
 	PrivateFile string
 }
 
 type Bucket struct {
-	Size          int //bucket数量
-	Channel       int //每个房间的channel
-	Room          int //每个bucket中的房价数量
-	RoutineAmount int //广播房间的channel数量
-	RoutineSize   int //每个channel长度
+	Size          int           //bucket数量
+	Channel       int           //每个房间的channel
+	Room          int           //每个bucket中的房价数量
+	RoutineAmount int           //广播房间的channel数量
+	RoutineSize   int           //每个channel长度
+	ReaperTick    time.Duration //心跳超时检查时间轮的刻度
+	ReaperSlots   int           //心跳超时检查时间轮的槽数
+	RoomBatch     int           //房间消息合并成一个OpRaw的最大条数, 小于2不合并
+	RoomSignal    time.Duration //房间消息合并的最长等待时间
+	MaxRooms      int           //每个连接最多加入的房间数, 0不限制
 }
 
 // TCP is tcp config.
@@ -84,6 +129,10 @@ type TCP struct {
 	Writer       int
 	WriteBuf     int
 	WriteBufSize int
+	TlsOpen      bool
+	TlsBind      []string //tls监听的地址
+	CertFile     string
+	PrivateFile  string
 }
 
 type Mode struct {
@@ -91,7 +140,48 @@ type Mode struct {
 }
 
 type Protocol struct {
-	ProtoSize int
+	ProtoSize        int
+	AckWindow        int           //每个连接未确认下行消息的最大数量, 0表示不重传
+	AckTimeout       time.Duration //未确认消息的重传间隔
+	ResumeTimeout    time.Duration //断线后保留未确认消息等待重连的时间
+	ServerHeartbeat  time.Duration //向logic续期session的最小间隔, 需小于logic的redis expire
+	HandshakeTimeout time.Duration //建立连接后必须在该时间内完成认证
+	Versions         []*Version    //支持的协议版本, 为空时不限制版本
+	RateLimit        *RateLimit    //上行消息的速率限制, 为空不限制
+}
+
+// RateLimit is the upstream rate limit of a channel, a token bucket for all
+// ops and one for each configured op.
+type RateLimit struct {
+	Rate            float64       //每秒允许的上行消息数, 0不限制, 心跳不计入
+	Burst           int           //允许突发的消息数
+	Ops             []*OpRate     //单独限制的op, 同时计入总的速率
+	MaxViolations   int           //连接在窗口内超限的次数达到后断开并封禁ip, 0不断开
+	ViolationWindow time.Duration //统计超限次数的滑动窗口, 默认1分钟
+	BanTime         time.Duration //封禁ip的时间
+}
+
+// OpRate is the rate limit of an op.
+type OpRate struct {
+	Op    int32
+	Rate  float64
+	Burst int
+}
+
+// Version is the limits of a protocol version, negotiated at OpHandshake or OpAuth.
+type Version struct {
+	Ver               int32
+	MaxBodySize       int32   //上行body的最大长度
+	Ops               []int32 //允许的上行op, 为空不限制, 心跳总是允许
+	Compress          string  //下行body的压缩算法 gzip/zstd/snappy, 为空不压缩, websocket json模式不压缩
+	CompressThreshold int32   //body达到该长度才压缩
+}
+
+// RPCClient is logic RPC client config.
+type RPCClient struct {
+	Addr    string
+	Dial    time.Duration
+	Timeout time.Duration
 }
 
 // RPCServer is RPC server config.
@@ -104,4 +194,8 @@ type RPCServer struct {
 	ForceCloseWait    time.Duration
 	KeepAliveInterval time.Duration
 	KeepAliveTimeout  time.Duration
+	TlsOpen           bool
+	CertFile          string
+	PrivateFile       string
+	ClientCAFile      string //校验job客户端证书的CA, 不为空时开启mTLS
 }
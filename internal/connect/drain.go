@@ -0,0 +1,166 @@
+package connect
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	pb "go-im/api/connect"
+	"go-im/api/logic"
+	"go-im/api/protocol"
+	"go.uber.org/zap"
+)
+
+const (
+	_drainTick  = time.Second
+	_drainHints = 3
+	// _drainBatch the max conns disconnected in one rpc, under the grpc message limit.
+	_drainBatch = 1000
+)
+
+// errDraining the server is draining, the client is told to reconnect to other nodes.
+var errDraining = errors.New("server draining")
+
+// Draining get the OpReconnect body if the server is draining.
+func (s *Server) Draining() (hint []byte, ok bool) {
+	hint, ok = s.drainHint.Load().([]byte)
+	return
+}
+
+// Drain deregister the server, tell all the clients to reconnect to other
+// nodes, and close the rest after the grace period. The new clients are
+// told to reconnect once authed. It returns after all conns are closed.
+func (s *Server) Drain() {
+	s.drainOnce.Do(s.drain)
+}
+
+func (s *Server) drain() {
+	//撤销注册后续租协程会关闭etcd客户端, 先取其他节点作为重连提示
+	hint := s.reconnectHint()
+	//从etcd删除, logic不再分配客户端到本节点; 停止刷新负载, 否则可能重新写入
+	if s.register != nil {
+		s.stopRegister()
+		if err := s.register.Deregister(); err != nil {
+			s.log.Error("deregister err", zap.Error(err))
+		}
+	}
+	s.drainHint.Store(hint)
+	var conns int
+	for _, b := range s.buckets {
+		for _, ch := range b.Channels() {
+			_ = ch.Reply(&protocol.Proto{Ver: 1, Op: protocol.OpReconnect, Body: hint})
+			conns++
+		}
+	}
+	s.log.Info("draining", zap.Int("conns", conns), zap.ByteString("hint", hint))
+
+	var grace time.Duration
+	if s.c.Drain != nil {
+		grace = s.c.Drain.Grace
+	}
+	for deadline := time.Now().Add(grace); time.Now().Before(deadline) && s.connCount() > 0; {
+		time.Sleep(_drainTick)
+	}
+
+	//bucket不再放入新的连接, 关闭剩余的连接, 分批通知logic断开
+	var rest []*logic.Conn
+	for _, b := range s.buckets {
+		for _, ch := range b.Drain() {
+			atomic.StoreInt32(&ch.drained, 1)
+			rest = append(rest, &logic.Conn{Mid: ch.Mid, Key: ch.Key})
+			_ = ch.CloseConn()
+		}
+	}
+	for len(rest) > 0 {
+		n := len(rest)
+		if n > _drainBatch {
+			n = _drainBatch
+		}
+		if _, err := s.rpcClient.DisconnectBatch(context.Background(), &logic.DisconnectBatchReq{
+			Server: s.serverID,
+			Conns:  rest[:n],
+		}); err != nil {
+			s.log.Error("disconnect batch err", zap.Int("conns", n), zap.Error(err))
+		}
+		rest = rest[n:]
+	}
+}
+
+// connCount the connections of the server.
+func (s *Server) connCount() (n int) {
+	for _, b := range s.buckets {
+		n += b.ChannelCount()
+	}
+	return
+}
+
+// reconnectHint the OpReconnect body with the least loaded nodes.
+func (s *Server) reconnectHint() []byte {
+	var addrs []string
+	if ins, err := s.instances(); err != nil {
+		s.log.Error("get instances err", zap.Error(err))
+	} else {
+		var (
+			region string
+			n      = _drainHints
+		)
+		if s.c.Env != nil {
+			region = s.c.Env.Region
+		}
+		if s.c.Drain != nil && s.c.Drain.Hints > 0 {
+			n = s.c.Drain.Hints
+		}
+		addrs = alternateAddrs(ins, s.serverID, region, n)
+	}
+	b, _ := jsoniter.Marshal(&struct {
+		Addrs []string `json:"addrs"`
+	}{Addrs: addrs})
+	return b
+}
+
+// instances get the registered comets from etcd by the client of the register.
+func (s *Server) instances() ([]*pb.Instance, error) {
+	if s.register == nil {
+		return nil, nil
+	}
+	srvs, err := s.register.List(pb.DiscoveryPrefix)
+	if err != nil {
+		return nil, err
+	}
+	ins := make([]*pb.Instance, 0, len(srvs))
+	for _, val := range srvs {
+		if in, err := pb.ParseInstance(val); err == nil {
+			ins = append(ins, in)
+		}
+	}
+	return ins, nil
+}
+
+// alternateAddrs pick at most n addrs of other online nodes, the nodes in the
+// same region and with less load per weight go first.
+func alternateAddrs(ins []*pb.Instance, self, region string, n int) []string {
+	nodes := make([]*pb.Instance, 0, len(ins))
+	for _, in := range ins {
+		if in.Hostname == self || in.Offline || in.Weight <= 0 || len(in.Addrs) == 0 {
+			continue
+		}
+		nodes = append(nodes, in)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if ri, rj := nodes[i].Region == region, nodes[j].Region == region; ri != rj {
+			return ri
+		}
+		return float64(nodes[i].ConnCount)/float64(nodes[i].Weight) < float64(nodes[j].ConnCount)/float64(nodes[j].Weight)
+	})
+	addrs := make([]string, 0, n)
+	for _, in := range nodes {
+		if len(addrs) == n {
+			break
+		}
+		addrs = append(addrs, in.Addrs[0])
+	}
+	return addrs
+}
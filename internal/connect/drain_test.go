@@ -0,0 +1,95 @@
+package connect
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	pb "go-im/api/connect"
+	"go-im/api/logic"
+	"go-im/api/protocol"
+	"go-im/internal/connect/conf"
+	"go-im/pkg/log"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+func TestAlternateAddrs(t *testing.T) {
+	ins := []*pb.Instance{
+		{Hostname: "self", Region: "sh", Addrs: []string{"0.0.0.0"}, Weight: 10},
+		{Hostname: "a", Region: "bj", Addrs: []string{"1.1.1.1"}, Weight: 10},
+		{Hostname: "b", Region: "sh", Addrs: []string{"2.2.2.2"}, Weight: 10, ConnCount: 100},
+		{Hostname: "c", Region: "sh", Addrs: []string{"3.3.3.3"}, Weight: 20, ConnCount: 100},
+		{Hostname: "d", Region: "sh", Addrs: []string{"4.4.4.4"}, Weight: 10, Offline: true},
+	}
+	got := alternateAddrs(ins, "self", "sh", 3)
+	if want := []string{"3.3.3.3", "2.2.2.2", "1.1.1.1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v want %v", got, want)
+	}
+	if got = alternateAddrs(ins, "self", "sh", 1); len(got) != 1 {
+		t.Fatalf("got %v", got)
+	}
+}
+
+// drainLogic record the batch disconnected conns.
+type drainLogic struct {
+	logic.LogicClient
+	reqs []*logic.DisconnectBatchReq
+}
+
+func (l *drainLogic) DisconnectBatch(ctx context.Context, in *logic.DisconnectBatchReq, opts ...grpc.CallOption) (*logic.DisconnectBatchReply, error) {
+	l.reqs = append(l.reqs, in)
+	return &logic.DisconnectBatchReply{}, nil
+}
+
+func TestDrain(t *testing.T) {
+	rpc := new(drainLogic)
+	b := newTestBucket(t)
+	s := &Server{
+		c:         &conf.Config{Drain: &conf.Drain{}},
+		serverID:  "self",
+		buckets:   []*Bucket{b},
+		rpcClient: rpc,
+		log:       &log.Log{Logger: zap.NewNop()},
+	}
+	ch := NewChannel(&conf.Protocol{})
+	ch.Mid, ch.Key = 1, "key"
+	if err := b.Put("", ch); err != nil {
+		t.Fatal(err)
+	}
+	// the rest conns are disconnected in batches
+	for i := 0; i < _drainBatch; i++ {
+		c := NewChannel(&conf.Protocol{})
+		c.Key = strconv.Itoa(i)
+		if err := b.Put("", c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s.Drain()
+	p, _ := ch.Ready()
+	if p.Op != protocol.OpReconnect {
+		t.Fatalf("op %d", p.Op)
+	}
+	var hint struct {
+		Addrs []string `json:"addrs"`
+	}
+	if err := jsoniter.Unmarshal(p.Body, &hint); err != nil {
+		t.Fatal(err)
+	}
+	if len(rpc.reqs) != 2 || rpc.reqs[0].Server != "self" || len(rpc.reqs[0].Conns)+len(rpc.reqs[1].Conns) != _drainBatch+1 {
+		t.Fatalf("disconnect batches %d", len(rpc.reqs))
+	}
+	if ch.drained != 1 {
+		t.Fatal("channel not drained")
+	}
+	if _, ok := s.Draining(); !ok {
+		t.Fatal("server not draining")
+	}
+	// the channels authed after the sweep are not put
+	if err := b.Put("", NewChannel(&conf.Protocol{})); err != errDraining {
+		t.Fatalf("put after drain %v", err)
+	}
+}
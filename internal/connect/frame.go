@@ -0,0 +1,139 @@
+package connect
+
+import (
+	"encoding/base64"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"go-im/api/protocol"
+	"go-im/pkg/proto"
+)
+
+// Frame is a broadcast message encoded once per wire format and shared by
+// all the channels it's pushed to. The seq differs per channel, so it's
+// patched when the frame is written.
+// The body is also compressed once per codec the channels negotiated, and a
+// pre-compressed body is decompressed once for the channels without the codec.
+// A frame is immutable, and its pooled buffers are put back when the last
+// reference is released.
+type Frame struct {
+	p     *protocol.Proto
+	refs  int32
+	codec proto.Codec   // p.Body的压缩算法, 推送方可以预压缩
+	pkg   *proto.Buffer // tcp包, 也是websocket二进制模式的消息
+
+	encs [proto.NumCodecs]frameEnc // 按其他压缩算法重新编码的tcp包
+
+	plainOnce sync.Once
+	plain     *protocol.Proto // 未压缩的消息, 用于json模式和合并房间消息
+
+	jsonOnce sync.Once
+	json     *proto.Buffer // websocket json模式: json[:jsonSeq] + seq + json[jsonSeq:]
+	jsonSeq  int
+}
+
+// frameEnc is the frame re-encoded in a codec, buf is nil if it's the same as the pkg.
+type frameEnc struct {
+	once sync.Once
+	buf  *proto.Buffer
+}
+
+// NewFrame encode the proto, the frame is referenced once by the caller.
+func NewFrame(p *protocol.Proto) *Frame {
+	f := &Frame{p: p, refs: 1, codec: proto.CodecOf(p.Ver)}
+	f.pkg = encodeFrame(p)
+	return f
+}
+
+func encodeFrame(p *protocol.Proto) *proto.Buffer {
+	buf := proto.GetBuffer(proto.RawHeaderSize + len(p.Body))
+	proto.EncodeTo(buf.Bytes(), p)
+	return buf
+}
+
+// Retain add a reference.
+func (f *Frame) Retain() {
+	if f != nil {
+		atomic.AddInt32(&f.refs, 1)
+	}
+}
+
+// Release remove a reference, the buffers are put back to the pool by the last one.
+func (f *Frame) Release() {
+	if f == nil || atomic.AddInt32(&f.refs, -1) != 0 {
+		return
+	}
+	proto.PutBuffer(f.pkg)
+	proto.PutBuffer(f.json)
+	f.pkg, f.json = nil, nil
+	for i := range f.encs {
+		proto.PutBuffer(f.encs[i].buf)
+		f.encs[i].buf = nil
+	}
+}
+
+// plainProto get the proto with the body decompressed.
+func (f *Frame) plainProto() *protocol.Proto {
+	f.plainOnce.Do(func() {
+		f.plain = f.p
+		if f.codec == proto.CodecNone {
+			return
+		}
+		if body, c := encodeBody(f.p.Body, f.codec, proto.CodecNone, 0); c == proto.CodecNone {
+			f.plain = &protocol.Proto{Ver: proto.BaseVer(f.p.Ver), Op: f.p.Op, Body: body}
+		}
+	})
+	return f.plain
+}
+
+// packet get the tcp package in the codec, the body shorter than threshold is not compressed.
+func (f *Frame) packet(codec proto.Codec, threshold int) []byte {
+	if codec == f.codec || codec >= proto.NumCodecs || f.codec >= proto.NumCodecs ||
+		(f.codec == proto.CodecNone && len(f.p.Body) < threshold) {
+		return f.pkg.Bytes()
+	}
+	enc := &f.encs[codec]
+	enc.once.Do(func() {
+		body, c := encodeBody(f.p.Body, f.codec, codec, 0)
+		if c != f.codec {
+			enc.buf = encodeFrame(&protocol.Proto{Ver: proto.WithCodec(f.p.Ver, c), Op: f.p.Op, Body: body})
+		}
+	})
+	if enc.buf == nil {
+		return f.pkg.Bytes()
+	}
+	return enc.buf.Bytes()
+}
+
+// writeTCP write the tcp package in the codec, also used by the binary websocket message.
+func (f *Frame) writeTCP(w io.Writer, seq int32, codec proto.Codec, threshold int) error {
+	return proto.WriteFrame(w, f.packet(codec, threshold), seq)
+}
+
+// writeJSON write the json of the proto, the same as jsoniter.Marshal.
+func (f *Frame) writeJSON(w io.Writer, seq int32) error {
+	f.jsonOnce.Do(f.encodeJSON)
+	b := f.json.Bytes()
+	//拼接成一个消息写出, seq最长11个字符
+	buf := proto.GetBuffer(len(b) + 11)
+	msg := append(buf.Bytes()[:0], b[:f.jsonSeq]...)
+	msg = strconv.AppendInt(msg, int64(seq), 10)
+	msg = append(msg, b[f.jsonSeq:]...)
+	_, err := w.Write(msg)
+	proto.PutBuffer(buf)
+	return err
+}
+
+func (f *Frame) encodeJSON() {
+	p := f.plainProto()
+	head := `{"ver":` + strconv.Itoa(int(p.Ver)) + `,"op":` + strconv.Itoa(int(p.Op)) + `,"seq":`
+	tail := `}`
+	if len(p.Body) > 0 {
+		tail = `,"body":"` + base64.StdEncoding.EncodeToString(p.Body) + `"}`
+	}
+	f.json = proto.GetBuffer(len(head) + len(tail))
+	f.jsonSeq = copy(f.json.Bytes(), head)
+	copy(f.json.Bytes()[f.jsonSeq:], tail)
+}
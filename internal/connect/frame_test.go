@@ -0,0 +1,146 @@
+package connect
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"go-im/api/protocol"
+	"go-im/internal/connect/conf"
+	"go-im/pkg/proto"
+)
+
+func TestFrame(t *testing.T) {
+	p := &protocol.Proto{Ver: 1, Op: protocol.OpRaw, Body: []byte(`{"msg":"hello"}`)}
+	f := NewFrame(p)
+
+	var b bytes.Buffer
+	if err := f.writeTCP(&b, 7, proto.CodecNone, 0); err != nil {
+		t.Fatal(err)
+	}
+	got := new(protocol.Proto)
+	if err := proto.Decode(b.Bytes(), got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Seq != 7 || got.Op != p.Op || !bytes.Equal(got.Body, p.Body) {
+		t.Fatalf("tcp frame %v", got)
+	}
+
+	b.Reset()
+	if err := f.writeJSON(&b, 8); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := jsoniter.Marshal(&protocol.Proto{Ver: 1, Op: protocol.OpRaw, Seq: 8, Body: p.Body})
+	if b.String() != string(want) {
+		t.Fatalf("json frame %s want %s", b.String(), want)
+	}
+
+	// the channel holds the frame until written and acked
+	ch := NewChannel(&conf.Protocol{AckWindow: 4})
+	if err := ch.PushFrame(f); err != nil {
+		t.Fatal(err)
+	}
+	f.Release()
+	sp, sf := ch.Ready()
+	if sf != f || sp.Seq != 1 {
+		t.Fatalf("wrong signal %v %v", sp, sf)
+	}
+	sf.Release()
+	if f.pkg == nil {
+		t.Fatal("frame released before acked")
+	}
+	ch.Ack(sp.Seq)
+	if f.pkg != nil {
+		t.Fatal("frame not released after acked")
+	}
+}
+
+func TestFrameCompress(t *testing.T) {
+	body := bytes.Repeat([]byte(`{"msg":"hello"}`), 64)
+	zb, _ := proto.Compress(proto.CodecGzip, body)
+	// pre-compressed by the pusher
+	f := NewFrame(&protocol.Proto{Ver: proto.WithCodec(1, proto.CodecGzip), Op: protocol.OpRaw, Body: zb})
+	defer f.Release()
+	for _, c := range []struct {
+		codec     proto.Codec
+		threshold int
+	}{
+		{proto.CodecGzip, 0},
+		{proto.CodecZstd, 0},
+		{proto.CodecSnappy, 1 << 20},
+		{proto.CodecNone, 0},
+	} {
+		var b bytes.Buffer
+		if err := f.writeTCP(&b, 3, c.codec, c.threshold); err != nil {
+			t.Fatal(err)
+		}
+		got := new(protocol.Proto)
+		if err := proto.Decode(b.Bytes(), got); err != nil {
+			t.Fatal(err)
+		}
+		if proto.CodecOf(got.Ver) != c.codec || proto.BaseVer(got.Ver) != 1 || got.Seq != 3 {
+			t.Fatalf("%s ver %x seq %d", c.codec, got.Ver, got.Seq)
+		}
+		plain, err := proto.Decompress(c.codec, got.Body, len(body))
+		if err != nil || !bytes.Equal(plain, body) {
+			t.Fatalf("%s body %v", c.codec, err)
+		}
+	}
+	// json clients get the plain body
+	var b bytes.Buffer
+	if err := f.writeJSON(&b, 4); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := jsoniter.Marshal(&protocol.Proto{Ver: 1, Op: protocol.OpRaw, Seq: 4, Body: body})
+	if b.String() != string(want) {
+		t.Fatalf("json frame %s", b.String())
+	}
+}
+
+var fanoutBody = bytes.Repeat([]byte("x"), 512)
+
+// BenchmarkRoomFanout the cost of writing a room message to 1000 channels.
+func BenchmarkRoomFanout(b *testing.B) {
+	const channels = 1000
+	p := &protocol.Proto{Ver: 1, Op: protocol.OpRaw, Body: fanoutBody}
+	b.Run("proto/json", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for seq := int32(1); seq <= channels; seq++ {
+				np := &protocol.Proto{Ver: p.Ver, Op: p.Op, Seq: seq, Body: p.Body}
+				msg, _ := jsoniter.Marshal(np)
+				_, _ = io.Discard.Write(msg)
+			}
+		}
+	})
+	b.Run("frame/json", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			f := NewFrame(p)
+			for seq := int32(1); seq <= channels; seq++ {
+				_ = f.writeJSON(io.Discard, seq)
+			}
+			f.Release()
+		}
+	})
+	b.Run("proto/binary", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for seq := int32(1); seq <= channels; seq++ {
+				np := &protocol.Proto{Ver: p.Ver, Op: p.Op, Seq: seq, Body: p.Body}
+				_, _ = io.Discard.Write(proto.Encode(np))
+			}
+		}
+	})
+	b.Run("frame/binary", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			f := NewFrame(p)
+			for seq := int32(1); seq <= channels; seq++ {
+				_ = f.writeTCP(io.Discard, seq, proto.CodecNone, 0)
+			}
+			f.Release()
+		}
+	})
+}
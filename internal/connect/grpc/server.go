@@ -2,11 +2,15 @@ package grpc
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	pb "go-im/api/connect"
 	"go-im/internal/connect"
 	"go-im/internal/connect/conf"
+	"go-im/pkg/certs"
+	"go-im/pkg/tracing"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/keepalive"
 	"net"
 	"time"
@@ -21,7 +25,16 @@ func New(c *conf.RPCServer, s *connect.Server) *grpc.Server {
 		Timeout:               c.KeepAliveTimeout,
 		MaxConnectionAge:      c.MaxLifeTime,
 	})
-	srv := grpc.NewServer(keepParams)
+	//job推送时在metadata中携带trace上下文
+	opts := []grpc.ServerOption{keepParams, grpc.UnaryInterceptor(tracing.UnaryServerInterceptor())}
+	if c.TlsOpen {
+		tlsConf, err := serverTLS(c, s)
+		if err != nil {
+			panic(err)
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConf)))
+	}
+	srv := grpc.NewServer(opts...)
 	pb.RegisterCometServer(srv, &server{s})
 	lis, err := net.Listen(c.Network, c.Addr)
 	if err != nil {
@@ -35,6 +48,22 @@ func New(c *conf.RPCServer, s *connect.Server) *grpc.Server {
 	return srv
 }
 
+// serverTLS get the tls config, the job clients must present certificates
+// signed by the ClientCAFile if it's set.
+func serverTLS(c *conf.RPCServer, s *connect.Server) (*tls.Config, error) {
+	tlsConf, err := s.TLSConfig(c.CertFile, c.PrivateFile)
+	if err != nil {
+		return nil, err
+	}
+	if c.ClientCAFile != "" {
+		if tlsConf.ClientCAs, err = certs.LoadCertPool(c.ClientCAFile); err != nil {
+			return nil, err
+		}
+		tlsConf.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConf, nil
+}
+
 type server struct {
 	srv *connect.Server
 }
@@ -67,9 +96,12 @@ func (s server) Broadcast(ctx context.Context, req *pb.BroadcastReq) (*pb.Broadc
 	if req.Proto == nil {
 		return &pb.BroadcastReply{}, errors.New("参数错误")
 	}
+	//所有bucket共享一次编码
+	f := connect.NewFrame(req.Proto)
 	go func() {
+		defer f.Release()
 		for _, bucket := range s.srv.Buckets() {
-			bucket.Broadcast(req.Proto, req.ProtoOp)
+			bucket.Broadcast(f, req.ProtoOp)
 			if req.Speed > 0 {
 				t := bucket.ChannelCount() / int(req.Speed)
 				time.Sleep(time.Duration(t) * time.Second)
@@ -83,9 +115,11 @@ func (s server) BroadcastRoom(ctx context.Context, req *pb.BroadcastRoomReq) (*p
 	if req.Proto == nil || req.RoomID == "" {
 		return nil, errors.New("参数错误")
 	}
+	f := connect.NewFrame(req.Proto)
 	for _, bucket := range s.srv.Buckets() {
-		bucket.BroadcastRoom(req)
+		bucket.BroadcastRoom(req.RoomID, f)
 	}
+	f.Release()
 
 	return &pb.BroadcastRoomReply{}, nil
 }
@@ -101,3 +135,10 @@ func (s server) Rooms(ctx context.Context, req *pb.RoomsReq) (*pb.RoomsReply, er
 	}
 	return &pb.RoomsReply{Rooms: roomIds}, nil
 }
+
+func (s server) Kick(ctx context.Context, req *pb.KickReq) (*pb.KickReply, error) {
+	if len(req.Keys) == 0 {
+		return nil, errors.New("参数错误")
+	}
+	return &pb.KickReply{Kicked: int32(s.srv.Kick(req.Keys, req.Reason))}, nil
+}
@@ -0,0 +1,74 @@
+package connect
+
+import (
+	"sort"
+
+	"go-im/api/protocol"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// policyReject reject the new connection when over the limit.
+	policyReject = "reject"
+	// policyKick kick the oldest connections to make room for the new one.
+	policyKick = "kick"
+)
+
+var (
+	// errTooManyConns the ip has too many connections on this server.
+	errTooManyConns = status.Error(codes.ResourceExhausted, "too many connections")
+	// errKicked the connection is kicked by a newer one over the limit.
+	errKicked = status.Error(codes.ResourceExhausted, "kicked by new connection")
+	// errTooManyRooms the channel joined too many rooms.
+	errTooManyRooms = status.Error(codes.ResourceExhausted, "too many rooms")
+)
+
+// ipConns the connections of the ip in all buckets.
+func (s *Server) ipConns(ip string) (n int) {
+	for _, b := range s.buckets {
+		n += int(b.IPConns(ip))
+	}
+	return
+}
+
+// limitIP enforce the max connections per ip on this server, it's called
+// after auth and before the channel is put into the bucket.
+func (s *Server) limitIP(ip string) error {
+	lc := s.c.Limit
+	if lc == nil || lc.MaxIPConns <= 0 || s.ipConns(ip) < lc.MaxIPConns {
+		return nil
+	}
+	if lc.IPPolicy != policyKick {
+		limitStats.WithLabelValues("ip_reject").Inc()
+		return errTooManyConns
+	}
+	//已被踢掉但还未关闭的连接不再计数
+	var chs []*Channel
+	for _, b := range s.buckets {
+		for _, ch := range b.Channels() {
+			if ch.IP == ip && !ch.Kicked() {
+				chs = append(chs, ch)
+			}
+		}
+	}
+	for _, ch := range oldest(chs, len(chs)-lc.MaxIPConns+1) {
+		ch.Kick(protocol.OpDisconnectReply, errBody(errKicked))
+		limitStats.WithLabelValues("ip_kick").Inc()
+	}
+	return nil
+}
+
+// oldest get at most n channels connected earliest.
+func oldest(chs []*Channel, n int) []*Channel {
+	if n <= 0 {
+		return nil
+	}
+	sort.Slice(chs, func(i, j int) bool {
+		return chs[i].created.Before(chs[j].created)
+	})
+	if n > len(chs) {
+		n = len(chs)
+	}
+	return chs[:n]
+}
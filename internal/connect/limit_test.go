@@ -0,0 +1,47 @@
+package connect
+
+import (
+	"testing"
+
+	"go-im/api/protocol"
+	"go-im/internal/connect/conf"
+)
+
+func TestLimitIP(t *testing.T) {
+	b := newTestBucket(t)
+	s := &Server{
+		c:       &conf.Config{Limit: &conf.Limit{MaxIPConns: 2, IPPolicy: policyReject}},
+		buckets: []*Bucket{b},
+	}
+	var chs []*Channel
+	for _, key := range []string{"a", "b"} {
+		ch := NewChannel(&conf.Protocol{})
+		ch.Key, ch.IP = key, "1.1.1.1"
+		if err := b.Put("", ch); err != nil {
+			t.Fatal(err)
+		}
+		chs = append(chs, ch)
+	}
+	if err := s.limitIP("2.2.2.2"); err != nil {
+		t.Fatalf("other ip limited %v", err)
+	}
+	if err := s.limitIP("1.1.1.1"); err != errTooManyConns {
+		t.Fatalf("not rejected %v", err)
+	}
+
+	// the oldest one is kicked and notified
+	s.c.Limit.IPPolicy = policyKick
+	if err := s.limitIP("1.1.1.1"); err != nil {
+		t.Fatal(err)
+	}
+	if !chs[0].Kicked() || chs[1].Kicked() {
+		t.Fatal("oldest not kicked")
+	}
+	if p, _ := chs[0].Ready(); p.Op != protocol.OpDisconnectReply {
+		t.Fatalf("op %d", p.Op)
+	}
+	// the kicked one is not counted again
+	if err := s.limitIP("1.1.1.1"); err != nil || chs[1].Kicked() {
+		t.Fatal("kicked twice")
+	}
+}
@@ -0,0 +1,56 @@
+package connect
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// authFailures the failed auths by the rpc code from logic.
+	authFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "goim",
+		Subsystem: "connect",
+		Name:      "auth_failures_total",
+		Help:      "Auth failures by the code returned by logic.",
+	}, []string{"code"})
+	// signalDrops the messages dropped because the signal channel is full.
+	signalDrops = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "goim",
+		Subsystem: "connect",
+		Name:      "signal_drops_total",
+		Help:      "Messages dropped because the signal channel of the channel is full.",
+	})
+	// limitStats 超过连接数和速率限制的次数, ip_reject/ip_kick/rate_limited/ip_ban
+	limitStats = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "goim",
+		Subsystem: "connect",
+		Name:      "limited_total",
+		Help:      "Connections and messages limited by the reason.",
+	}, []string{"reason"})
+
+	connsDesc = prometheus.NewDesc("goim_connect_conns", "Connections per bucket.", []string{"bucket"}, nil)
+	roomsDesc = prometheus.NewDesc("goim_connect_rooms", "Rooms per bucket.", []string{"bucket"}, nil)
+)
+
+func init() {
+	prometheus.MustRegister(authFailures, signalDrops, limitStats)
+}
+
+// bucketCollector collect the conns and rooms of the buckets when scraped.
+type bucketCollector struct {
+	buckets []*Bucket
+}
+
+func (c *bucketCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- connsDesc
+	ch <- roomsDesc
+}
+
+func (c *bucketCollector) Collect(ch chan<- prometheus.Metric) {
+	for i, b := range c.buckets {
+		idx := strconv.Itoa(i)
+		ch <- prometheus.MustNewConstMetric(connsDesc, prometheus.GaugeValue, float64(b.ChannelCount()), idx)
+		ch <- prometheus.MustNewConstMetric(roomsDesc, prometheus.GaugeValue, float64(b.RoomCount()), idx)
+	}
+}
@@ -0,0 +1,27 @@
+package connect
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go-im/internal/connect/conf"
+)
+
+func TestBucketCollector(t *testing.T) {
+	bs := []*Bucket{newTestBucket(t), newTestBucket(t)}
+	ch := NewChannel(&conf.Protocol{})
+	ch.Key = "a"
+	if err := bs[1].Put("live://1", ch); err != nil {
+		t.Fatal(err)
+	}
+	want := `
+# HELP goim_connect_conns Connections per bucket.
+# TYPE goim_connect_conns gauge
+goim_connect_conns{bucket="0"} 0
+goim_connect_conns{bucket="1"} 1
+`
+	if err := testutil.CollectAndCompare(&bucketCollector{buckets: bs}, strings.NewReader(want), "goim_connect_conns"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -3,34 +3,96 @@ package connect
 import (
 	"context"
 	"fmt"
+	jsoniter "github.com/json-iterator/go"
 	"go-im/api/logic"
 	"go-im/api/protocol"
+	"go-im/pkg/opset"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/status"
 	"strconv"
 	"strings"
 	"time"
 )
 
-// Connect connected a connection.
-func (s *Server) Connect(c context.Context, p *protocol.Proto, cookie string) (mid int64, key, rid string, accepts []int32, heartbeat time.Duration, err error) {
-	return 11022, "inson", "ooodi", []int32{15314}, time.Duration(3), nil
-
+// Connect connected a connection, pl limits the ops and rooms the client may subscribe or join.
+func (s *Server) Connect(c context.Context, p *protocol.Proto, cookie string) (mid int64, key, rid string, accepts []int32, pl policy, heartbeat time.Duration, err error) {
 	reply, err := s.rpcClient.Connect(c, &logic.ConnectReq{
 		Server: s.serverID,
 		Cookie: cookie,
 		Token:  p.Body,
 	})
 	if err != nil {
+		authFailures.WithLabelValues(status.Code(err).String()).Inc()
+		return
+	}
+	if pl, err = newPolicy(reply.Allow, reply.Rooms); err != nil {
 		return
 	}
-	return reply.Mid, reply.Key, reply.RoomID, reply.Accepts, time.Duration(reply.Heartbeat), nil
+	return reply.Mid, reply.Key, reply.RoomID, reply.Accepts, pl, time.Duration(reply.Heartbeat), nil
+}
+
+var (
+	// errSessionRevoked the session was revoked by logic, client must auth again.
+	errSessionRevoked = status.Error(codes.Unauthenticated, "session revoked")
+	// errRoomNotAllowed the room is not in the rooms allowed by logic.
+	errRoomNotAllowed = status.Error(codes.PermissionDenied, "room not allowed")
+)
+
+// Heartbeat renew the session in logic.
+func (s *Server) Heartbeat(ctx context.Context, mid int64, key string) (revoked bool, err error) {
+	reply, err := s.rpcClient.Heartbeat(ctx, &logic.HeartbeatReq{
+		Server: s.serverID,
+		Mid:    mid,
+		Key:    key,
+	})
+	if err != nil {
+		s.log.Error(fmt.Sprintf("key: %s mid: %d heartbeat", key, mid), zap.Error(err))
+		return
+	}
+	return reply.Revoked, nil
+}
+
+// Registered tell logic the channel is put into the bucket, logic replays the
+// offline messages only after that, or they are pushed before the channel exists.
+func (s *Server) Registered(ctx context.Context, ch *Channel) {
+	if _, err := s.rpcClient.Heartbeat(ctx, &logic.HeartbeatReq{
+		Server:     s.serverID,
+		Mid:        ch.Mid,
+		Key:        ch.Key,
+		Registered: true,
+	}); err != nil {
+		s.log.Error(fmt.Sprintf("key: %s mid: %d registered", ch.Key, ch.Mid), zap.Error(err))
+	}
+}
+
+// heartbeatReply set the proto to the heartbeat reply, the body is the online
+// count of the primary room.
+func heartbeatReply(ch *Channel, p *protocol.Proto) {
+	p.Op = protocol.OpHeartbeatReply
+	p.Body = nil
+	if room := ch.Room(); room != nil {
+		p.Body = []byte(strconv.Itoa(int(room.OnlineNum())))
+	}
+}
+
+// errBody encode the rpc error as reply body, like {"code":16,"message":"auth failed"}.
+func errBody(err error) []byte {
+	st := status.Convert(err)
+	b, _ := jsoniter.Marshal(&struct {
+		Code    int32  `json:"code"`
+		Message string `json:"message"`
+	}{
+		Code:    int32(st.Code()),
+		Message: st.Message(),
+	})
+	return b
 }
 
 // Disconnect disconnected a connection.
 func (s *Server) Disconnect(c context.Context, mid int64, key string) (err error) {
-	return nil
 	_, err = s.rpcClient.Disconnect(context.Background(), &logic.DisconnectReq{
 		Server: s.serverID,
 		Mid:    mid,
@@ -42,65 +104,107 @@ func (s *Server) Disconnect(c context.Context, mid int64, key string) (err error
 func (s *Server) Operate(ctx context.Context, p *protocol.Proto, b *Bucket, ch *Channel) error {
 	switch p.Op {
 	case protocol.OpChangeRoom:
-		if err := b.ChangeRoom(string(p.Body), ch); err != nil {
+		//认证后切换房间同样受logic下发的房间列表限制
+		if room := string(p.Body); room != "" && !ch.policy.allowRoom(room) {
+			p.Body = errBody(errRoomNotAllowed)
+		} else if err := b.ChangeRoom(room, ch); err != nil {
 			s.log.Error("change room err",
-				zap.String("room_id", string(p.Body)),
+				zap.String("room_id", room),
 				zap.String("ch key", ch.Key),
 				zap.Error(err))
+			p.Body = errBody(err)
 		}
 
 		p.Op = protocol.OpChangeRoomReply
+	case protocol.OpJoinRoom:
+		//加入的房间同样受logic下发的房间列表限制, 有一个不允许则都不加入
+		if ids := splitRooms(string(p.Body)); !ch.policy.allowRooms(ids) {
+			p.Body = errBody(errRoomNotAllowed)
+		} else if err := b.JoinRoom(ch, ids...); err != nil {
+			p.Body = errBody(err)
+		}
+		p.Op = protocol.OpJoinRoomReply
+	case protocol.OpLeaveRoom:
+		b.LeaveRoom(ch, splitRooms(string(p.Body))...)
+		p.Op = protocol.OpLeaveRoomReply
 	case protocol.OpSub:
-		if ops, err := SplitInt32s(string(p.Body), ","); err == nil {
-			ch.Watch(ops...)
+		//body如"1000,2000-2999", 回复实际订阅的op, 不允许的被忽略; 格式错误回复OpError
+		if ops, err := opset.Parse(string(p.Body)); err != nil {
+			p.Op = protocol.OpError
+			p.Body = errBody(status.Error(codes.InvalidArgument, err.Error()))
+		} else {
+			p.Op = protocol.OpSubReply
+			p.Body = []byte(ch.Subscribe(ops).String())
 		}
-		p.Op = protocol.OpSubReply
 	case protocol.OpUnsub:
-		if ops, err := SplitInt32s(string(p.Body), ","); err == nil {
-			ch.Watch(ops...)
+		if ops, err := opset.Parse(string(p.Body)); err != nil {
+			p.Op = protocol.OpError
+			p.Body = errBody(status.Error(codes.InvalidArgument, err.Error()))
+		} else {
+			p.Op = protocol.OpUnsubReply
+			ch.Unwatch(ops)
 		}
-		p.Op = protocol.OpUnsubReply
-	default: //发送到logic(真正发送消息是http请求)  默认为发送消息
-		if err := s.Receive(ctx, ch.Mid, p); err != nil {
-			s.log.Error(fmt.Sprintf("s.Report(%d) op:%d", ch.Mid, p.Op), zap.Error(err))
+	case protocol.OpMsgAck:
+		ch.Ack(p.Seq)
+		p.Body = nil
+		p.Op = protocol.OpMsgAckReply
+	case protocol.OpOfflineAck:
+		if err := s.Receive(ctx, ch, p); err != nil {
+			s.log.Error(fmt.Sprintf("s.Receive(%d) op:%d", ch.Mid, p.Op), zap.Error(err))
 		}
 		p.Body = nil
-		p.Op = protocol.OpUnsubReply
+		p.Op = protocol.OpOfflineAckReply
+	default: //发送到logic 默认为发送消息
+		if err := s.Receive(ctx, ch, p); err != nil {
+			s.log.Error(fmt.Sprintf("s.Receive(%d) op:%d", ch.Mid, p.Op), zap.Error(err))
+			p.Body = errBody(err)
+		} else {
+			p.Body = nil
+		}
+		p.Op = protocol.OpSendMsgReply
 	}
 	return nil
 }
 
 // Receive receive a message.
-func (s *Server) Receive(ctx context.Context, mid int64, p *protocol.Proto) (err error) {
-	_, err = s.rpcClient.Receive(ctx, &logic.ReceiveReq{Mid: mid, Proto: p})
+func (s *Server) Receive(ctx context.Context, ch *Channel, p *protocol.Proto) (err error) {
+	_, err = s.rpcClient.Receive(ctx, &logic.ReceiveReq{Mid: ch.Mid, Proto: p, Rooms: ch.Rooms()})
 	return
 }
 
-// SplitInt32s split string into int32 slice.
-func SplitInt32s(s, p string) ([]int32, error) {
-	if s == "" {
-		return nil, nil
-	}
-	sArr := strings.Split(s, p)
-	res := make([]int32, 0, len(sArr))
-	for _, sc := range sArr {
-		i, err := strconv.ParseInt(sc, 10, 32)
-		if err != nil {
-			return nil, err
+// splitRooms split the room ids joined by comma, the empty ones are dropped.
+func splitRooms(s string) []string {
+	var ids []string
+	for _, id := range strings.Split(s, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
 		}
-		res = append(res, int32(i))
 	}
-	return res, nil
+	return ids
 }
 
 // RenewOnline renew room online.
-func (s *Server) RenewOnline(ctx context.Context, serverID string, roomCount map[string]int32) (allRoom map[string]int32, err error) {
+func (s *Server) RenewOnline(ctx context.Context, serverID string, roomCount map[string]int32, ipCount, connCount int32) (allRoom map[string]int32, err error) {
 	reply, err := s.rpcClient.RenewOnline(ctx, &logic.OnlineReq{
 		Server:    s.serverID,
 		RoomCount: roomCount,
+		IpCount:   ipCount,
+		ConnCount: connCount,
 	}, grpc.UseCompressor(gzip.Name))
 	if err != nil {
 		return
 	}
 	return reply.AllRoomCount, nil
 }
+
+// Kick send OpKicked with the reason to the keys then close them, the
+// mappings are removed by Disconnect when the conns are closed.
+func (s *Server) Kick(keys []string, reason string) (kicked int) {
+	body := errBody(status.Error(codes.PermissionDenied, reason))
+	for _, key := range keys {
+		if ch := s.Bucket(key).Channel(key); ch != nil && ch.Kick(protocol.OpKicked, body) {
+			kicked++
+		}
+	}
+	return
+}
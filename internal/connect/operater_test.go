@@ -0,0 +1,133 @@
+package connect
+
+import (
+	"context"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"go-im/api/protocol"
+	"go-im/internal/connect/conf"
+	"google.golang.org/grpc/codes"
+)
+
+func TestKick(t *testing.T) {
+	b := newTestBucket(t)
+	s := &Server{buckets: []*Bucket{b}}
+	ch := NewChannel(&conf.Protocol{})
+	ch.Key = "a"
+	if err := b.Put("", ch); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.Kick([]string{"a", "missing"}, "spam"); n != 1 {
+		t.Fatalf("kicked %d", n)
+	}
+	p, _ := ch.Ready()
+	var body struct {
+		Message string `json:"message"`
+	}
+	if err := jsoniter.Unmarshal(p.Body, &body); err != nil {
+		t.Fatal(err)
+	}
+	if p.Op != protocol.OpKicked || body.Message != "spam" {
+		t.Fatalf("kick reply op %d body %s", p.Op, p.Body)
+	}
+	if p, _ = ch.Ready(); p != protoClose {
+		t.Fatal("conn not closed after kicked")
+	}
+	if n := s.Kick([]string{"a"}, "spam"); n != 0 {
+		t.Fatalf("kicked twice %d", n)
+	}
+}
+
+func TestChangeRoomPolicy(t *testing.T) {
+	b := newTestBucket(t)
+	s := &Server{buckets: []*Bucket{b}}
+	ch := NewChannel(&conf.Protocol{})
+	ch.Key = "a"
+	ch.policy, _ = newPolicy(nil, []string{"live://1"})
+	if err := b.Put("live://1", ch); err != nil {
+		t.Fatal(err)
+	}
+	p := &protocol.Proto{Ver: 1, Op: protocol.OpChangeRoom, Body: []byte("live://2")}
+	if err := s.Operate(context.Background(), p, b, ch); err != nil {
+		t.Fatal(err)
+	}
+	if p.Op != protocol.OpChangeRoomReply || ch.Room().Id != "live://1" || b.Room("live://2") != nil {
+		t.Fatalf("changed to a room not allowed %v", ch.Rooms())
+	}
+	var body struct {
+		Code int32 `json:"code"`
+	}
+	if err := jsoniter.Unmarshal(p.Body, &body); err != nil || body.Code != int32(codes.PermissionDenied) {
+		t.Fatalf("reply body %s", p.Body)
+	}
+	// leaving the room is always allowed
+	p = &protocol.Proto{Ver: 1, Op: protocol.OpChangeRoom}
+	if err := s.Operate(context.Background(), p, b, ch); err != nil || ch.Room() != nil {
+		t.Fatalf("leave room %v", err)
+	}
+}
+
+func TestJoinRoomPolicy(t *testing.T) {
+	b := newTestBucket(t)
+	s := &Server{buckets: []*Bucket{b}}
+	ch := NewChannel(&conf.Protocol{})
+	ch.Key = "a"
+	ch.policy, _ = newPolicy(nil, []string{"live://1", "live://2"})
+	if err := b.Put("live://1", ch); err != nil {
+		t.Fatal(err)
+	}
+	p := &protocol.Proto{Ver: 1, Op: protocol.OpJoinRoom, Body: []byte("live://2,live://3")}
+	if err := s.Operate(context.Background(), p, b, ch); err != nil {
+		t.Fatal(err)
+	}
+	if p.Op != protocol.OpJoinRoomReply || ch.InRoom("live://2") || ch.InRoom("live://3") {
+		t.Fatalf("joined a room not allowed %v", ch.Rooms())
+	}
+	p = &protocol.Proto{Ver: 1, Op: protocol.OpJoinRoom, Body: []byte("live://2")}
+	if err := s.Operate(context.Background(), p, b, ch); err != nil || !ch.InRoom("live://2") {
+		t.Fatalf("join allowed room %v %s", ch.Rooms(), p.Body)
+	}
+}
+
+func TestChangeRoomJoinFirst(t *testing.T) {
+	b := newTestBucket(t)
+	b.c.MaxRooms = 2
+	ch := NewChannel(&conf.Protocol{})
+	ch.Key = "a"
+	if err := b.Put("live://1", ch); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.JoinRoom(ch, "live://2"); err != nil {
+		t.Fatal(err)
+	}
+	// the old primary room is not counted
+	if err := b.ChangeRoom("live://3", ch); err != nil || ch.Room().Id != "live://3" || ch.InRoom("live://1") {
+		t.Fatalf("change room %v %v", err, ch.Rooms())
+	}
+	// the channel is kept as it was if the join fails
+	b.c.MaxRooms = 1
+	if err := b.ChangeRoom("live://4", ch); err != errTooManyRooms || ch.Room().Id != "live://3" || !ch.InRoom("live://2") {
+		t.Fatalf("failed change room %v %v", err, ch.Rooms())
+	}
+}
+
+func TestHeartbeatReply(t *testing.T) {
+	b := newTestBucket(t)
+	ch := NewChannel(&conf.Protocol{})
+	ch.Key = "a"
+	p := &protocol.Proto{Ver: 1, Op: protocol.OpHeartbeat, Body: []byte("ping")}
+	if heartbeatReply(ch, p); p.Op != protocol.OpHeartbeatReply || p.Body != nil {
+		t.Fatalf("reply without room %d %s", p.Op, p.Body)
+	}
+	if err := b.Put("live://1", ch); err != nil {
+		t.Fatal(err)
+	}
+	b.Room("live://1").OnlineCount = 10
+	if heartbeatReply(ch, p); string(p.Body) != "10" {
+		t.Fatalf("online body %s", p.Body)
+	}
+	if protocol.ProtoReady.Body != nil {
+		t.Fatal("ProtoReady changed")
+	}
+}
@@ -0,0 +1,170 @@
+package connect
+
+import (
+	"sync"
+	"time"
+
+	"go-im/api/protocol"
+	"go-im/internal/connect/conf"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errRateLimited the client sends upstream messages too fast.
+var errRateLimited = status.Error(codes.ResourceExhausted, "rate limited")
+
+// _violationWindow the default window counting the violations.
+const _violationWindow = time.Minute
+
+// tokenBucket allow rate messages per second with at most burst at once.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst)}
+}
+
+// allow take a token if there's any.
+func (b *tokenBucket) allow(now time.Time) bool {
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// slidingCounter count the events in the sliding window, it's estimated by the
+// counts of the current and the previous fixed windows.
+type slidingCounter struct {
+	window time.Duration
+	start  time.Time // 当前固定窗口的开始时间
+	cur    int
+	prev   int
+}
+
+// add add an event, returns the count in the window ending now.
+func (c *slidingCounter) add(now time.Time) int {
+	if c.start.IsZero() {
+		c.start = now
+	}
+	if elapsed := now.Sub(c.start); elapsed >= c.window {
+		n := elapsed / c.window
+		if n == 1 {
+			c.prev = c.cur
+		} else {
+			c.prev = 0
+		}
+		c.cur = 0
+		c.start = c.start.Add(n * c.window)
+	}
+	c.cur++
+	//上一个窗口按仍在滑动窗口内的比例计入
+	rest := c.window - now.Sub(c.start)
+	return c.cur + int(int64(c.prev)*int64(rest)/int64(c.window))
+}
+
+// limiter is the upstream rate limit of a channel, it's only used by the reader.
+type limiter struct {
+	all        *tokenBucket
+	ops        map[int32]*tokenBucket
+	violations slidingCounter
+}
+
+// newLimiter returns nil if no limit is configured.
+func newLimiter(c *conf.RateLimit) *limiter {
+	if c == nil || (c.Rate <= 0 && len(c.Ops) == 0) {
+		return nil
+	}
+	l := &limiter{ops: make(map[int32]*tokenBucket, len(c.Ops))}
+	if l.violations.window = c.ViolationWindow; l.violations.window <= 0 {
+		l.violations.window = _violationWindow
+	}
+	if c.Rate > 0 {
+		l.all = newTokenBucket(c.Rate, c.Burst)
+	}
+	for _, o := range c.Ops {
+		l.ops[o.Op] = newTokenBucket(o.Rate, o.Burst)
+	}
+	return l
+}
+
+// allow check the op limit and then the channel limit, heartbeat is only
+// limited if its op is configured.
+func (l *limiter) allow(op int32, now time.Time) bool {
+	if b, ok := l.ops[op]; ok && !b.allow(now) {
+		return false
+	}
+	if l.all == nil || op == protocol.OpHeartbeat {
+		return true
+	}
+	return l.all.allow(now)
+}
+
+// rateLimit check the upstream message against the limits of the channel,
+// the dropped message is turned into an OpError reply. If the channel violates
+// too many times in the window, its ip is banned for a while and closing is true.
+func (s *Server) rateLimit(ch *Channel, p *protocol.Proto) (ok, closing bool) {
+	now := time.Now()
+	if ch.limiter == nil || ch.limiter.allow(p.Op, now) {
+		return true, false
+	}
+	limitStats.WithLabelValues("rate_limited").Inc()
+	p.Op = protocol.OpError
+	p.Body = errBody(errRateLimited)
+	rc := s.c.Protocol.RateLimit
+	if rc.MaxViolations <= 0 || ch.limiter.violations.add(now) < rc.MaxViolations {
+		return false, false
+	}
+	s.bans.ban(ch.IP, now.Add(rc.BanTime))
+	limitStats.WithLabelValues("ip_ban").Inc()
+	s.log.Warn("ip banned", zap.String("ip", ch.IP), zap.String("key", ch.Key), zap.Int64("mid", ch.Mid))
+	return false, true
+}
+
+// banList the temporarily banned ips, shared by all buckets.
+type banList struct {
+	mu   sync.Mutex
+	bans map[string]time.Time //ip -> 解封时间
+}
+
+func newBanList() *banList {
+	return &banList{bans: make(map[string]time.Time)}
+}
+
+// ban ban the ip until the time, the expired ones are cleaned up meanwhile.
+func (l *banList) ban(ip string, until time.Time) {
+	now := time.Now()
+	l.mu.Lock()
+	for k, t := range l.bans {
+		if !now.Before(t) {
+			delete(l.bans, k)
+		}
+	}
+	if until.After(now) {
+		l.bans[ip] = until
+	}
+	l.mu.Unlock()
+}
+
+// banned reports whether the ip is banned now.
+func (l *banList) banned(ip string) bool {
+	l.mu.Lock()
+	until, ok := l.bans[ip]
+	l.mu.Unlock()
+	return ok && time.Now().Before(until)
+}
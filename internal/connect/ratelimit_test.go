@@ -0,0 +1,89 @@
+package connect
+
+import (
+	"testing"
+	"time"
+
+	"go-im/api/protocol"
+	"go-im/internal/connect/conf"
+	"go-im/pkg/log"
+	"go.uber.org/zap"
+)
+
+func TestLimiter(t *testing.T) {
+	l := newLimiter(&conf.RateLimit{
+		Rate:  2,
+		Burst: 2,
+		Ops:   []*conf.OpRate{{Op: protocol.OpChangeRoom, Rate: 1, Burst: 1}},
+	})
+	now := time.Now()
+	if !l.allow(protocol.OpSendMsg, now) || !l.allow(protocol.OpSendMsg, now) {
+		t.Fatal("burst not allowed")
+	}
+	if l.allow(protocol.OpSendMsg, now) {
+		t.Fatal("over burst allowed")
+	}
+	// heartbeat is not limited by the channel rate
+	if !l.allow(protocol.OpHeartbeat, now) {
+		t.Fatal("heartbeat limited")
+	}
+	now = now.Add(500 * time.Millisecond)
+	if !l.allow(protocol.OpSendMsg, now) || l.allow(protocol.OpSendMsg, now) {
+		t.Fatal("tokens not refilled by rate")
+	}
+	// the op limit applies before the channel limit
+	now = now.Add(time.Second)
+	if !l.allow(protocol.OpChangeRoom, now) || l.allow(protocol.OpChangeRoom, now) {
+		t.Fatal("op not limited")
+	}
+	if newLimiter(&conf.RateLimit{}) != nil {
+		t.Fatal("limiter without limits")
+	}
+}
+
+func TestRateLimitBan(t *testing.T) {
+	pc := &conf.Protocol{RateLimit: &conf.RateLimit{Rate: 1, Burst: 1, MaxViolations: 2, BanTime: time.Minute}}
+	s := &Server{
+		c:    &conf.Config{Protocol: pc},
+		bans: newBanList(),
+		log:  &log.Log{Logger: zap.NewNop()},
+	}
+	ch := NewChannel(pc)
+	ch.IP = "1.1.1.1"
+	p := &protocol.Proto{Op: protocol.OpSendMsg}
+	if ok, _ := s.rateLimit(ch, p); !ok {
+		t.Fatal("first message limited")
+	}
+	if ok, closing := s.rateLimit(ch, p); ok || closing || p.Op != protocol.OpError {
+		t.Fatalf("not limited ok:%v closing:%v op:%d", ok, closing, p.Op)
+	}
+	p.Op = protocol.OpSendMsg
+	if _, closing := s.rateLimit(ch, p); !closing {
+		t.Fatal("not closed after violations")
+	}
+	if !s.bans.banned("1.1.1.1") || s.bans.banned("2.2.2.2") {
+		t.Fatal("ip not banned")
+	}
+	s.bans.ban("2.2.2.2", time.Now().Add(-time.Second))
+	if s.bans.banned("2.2.2.2") {
+		t.Fatal("expired ban")
+	}
+}
+
+func TestSlidingCounter(t *testing.T) {
+	c := slidingCounter{window: time.Minute}
+	now := time.Now()
+	for i := 1; i <= 3; i++ {
+		if n := c.add(now); n != i {
+			t.Fatalf("add %d got %d", i, n)
+		}
+	}
+	// half of the previous window is still in the sliding window
+	if n := c.add(now.Add(90 * time.Second)); n != 2 {
+		t.Fatalf("sliding count %d", n)
+	}
+	// the old violations are forgotten
+	if n := c.add(now.Add(10 * time.Minute)); n != 1 {
+		t.Fatalf("count after windows %d", n)
+	}
+}
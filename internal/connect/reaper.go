@@ -0,0 +1,116 @@
+package connect
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// _reaperTick the default tick of the wheel.
+	_reaperTick = time.Second
+	// _reaperSlots the default slots of the wheel.
+	_reaperSlots = 64
+)
+
+// Reaper is a timing wheel which closes the channels that have not read
+// anything from client in their heartbeat timeout.
+// Reading only updates the channel's last read time, the channel is checked
+// when the wheel reaches its slot and rescheduled if it's still alive.
+type Reaper struct {
+	lock   sync.Mutex
+	tick   time.Duration
+	slots  []map[*Channel]int // channel -> rounds left
+	where  map[*Channel]int   // channel -> slot
+	pos    int
+	expire func(ch *Channel)
+}
+
+// NewReaper new a reaper with size slots, the wheel moves a slot every tick.
+// The defaults are used if tick or size is not configured.
+func NewReaper(tick time.Duration, size int, expire func(ch *Channel)) *Reaper {
+	if tick <= 0 {
+		tick = _reaperTick
+	}
+	if size <= 0 {
+		size = _reaperSlots
+	}
+	r := &Reaper{
+		tick:   tick,
+		slots:  make([]map[*Channel]int, size),
+		where:  make(map[*Channel]int),
+		expire: expire,
+	}
+	for i := range r.slots {
+		r.slots[i] = make(map[*Channel]int)
+	}
+	go r.run()
+	return r
+}
+
+// Add start tracking the channel by its heartbeat.
+func (r *Reaper) Add(ch *Channel) {
+	r.lock.Lock()
+	r.del(ch)
+	r.schedule(ch, ch.hb)
+	r.lock.Unlock()
+}
+
+// Del stop tracking the channel.
+func (r *Reaper) Del(ch *Channel) {
+	r.lock.Lock()
+	r.del(ch)
+	r.lock.Unlock()
+}
+
+func (r *Reaper) del(ch *Channel) {
+	if slot, ok := r.where[ch]; ok {
+		delete(r.slots[slot], ch)
+		delete(r.where, ch)
+	}
+}
+
+func (r *Reaper) schedule(ch *Channel, d time.Duration) {
+	ticks := int((d + r.tick - 1) / r.tick)
+	if ticks < 1 {
+		ticks = 1
+	}
+	slot := (r.pos + ticks) % len(r.slots)
+	r.slots[slot][ch] = (ticks - 1) / len(r.slots)
+	r.where[ch] = slot
+}
+
+func (r *Reaper) run() {
+	ticker := time.NewTicker(r.tick)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		r.advance(now)
+	}
+}
+
+// advance move the wheel a slot, expire the channels idle too long.
+func (r *Reaper) advance(now time.Time) {
+	var expired, alive []*Channel
+	r.lock.Lock()
+	r.pos = (r.pos + 1) % len(r.slots)
+	slot := r.slots[r.pos]
+	for ch, rounds := range slot {
+		if rounds > 0 {
+			slot[ch] = rounds - 1
+			continue
+		}
+		delete(slot, ch)
+		delete(r.where, ch)
+		if now.Sub(ch.LastRead()) < ch.hb {
+			alive = append(alive, ch)
+			continue
+		}
+		expired = append(expired, ch)
+	}
+	for _, ch := range alive {
+		r.schedule(ch, ch.hb-now.Sub(ch.LastRead()))
+	}
+	r.lock.Unlock()
+	for _, ch := range expired {
+		r.expire(ch)
+	}
+}
@@ -0,0 +1,76 @@
+package connect
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-im/internal/connect/conf"
+)
+
+func TestReaper(t *testing.T) {
+	// the wheel is moved by hand, the real ticker never fires
+	const tick = time.Hour
+	var expired []*Channel
+	r := NewReaper(tick, 4, func(ch *Channel) { expired = append(expired, ch) })
+	newCh := func(hb time.Duration) *Channel {
+		ch := NewChannel(&conf.Protocol{})
+		ch.hb = hb
+		ch.Touch()
+		r.Add(ch)
+		return ch
+	}
+	idle, active, long, gone := newCh(3*tick), newCh(3*tick), newCh(10*tick), newCh(2*tick)
+	r.Del(gone)
+	start := time.Now()
+	advance := func(n int) {
+		r.advance(start.Add(time.Duration(n) * tick))
+	}
+	check := func(n int, want ...*Channel) {
+		t.Helper()
+		if len(expired) != len(want) {
+			t.Fatalf("tick %d expired %d want %d", n, len(expired), len(want))
+		}
+		for i, ch := range want {
+			if expired[i] != ch {
+				t.Fatalf("tick %d expired wrong channel %d", n, i)
+			}
+		}
+		expired = expired[:0]
+	}
+
+	for n := 1; n <= 2; n++ {
+		advance(n)
+		check(n)
+	}
+	// the active channel read something and is rescheduled
+	atomic.StoreInt64(&active.lastRead, start.Add(2*tick).UnixNano())
+	advance(3)
+	check(3, idle)
+	advance(4)
+	check(4)
+	advance(5)
+	check(5, active)
+	// the heartbeat longer than the wheel takes more rounds
+	for n := 6; n <= 9; n++ {
+		advance(n)
+		check(n)
+	}
+	advance(10)
+	check(10, long)
+	if len(r.where) != 0 {
+		t.Fatalf("channels still tracked %d", len(r.where))
+	}
+}
+
+func TestReaperDefaults(t *testing.T) {
+	// the config without the reaper keys must not panic
+	r := NewReaper(0, 0, func(ch *Channel) {})
+	if r.tick != _reaperTick || len(r.slots) != _reaperSlots {
+		t.Fatalf("tick %v slots %d", r.tick, len(r.slots))
+	}
+	ch := NewChannel(&conf.Protocol{})
+	ch.hb = time.Minute
+	r.Add(ch)
+	r.Del(ch)
+}
@@ -2,15 +2,23 @@ package connect
 
 import (
 	"github.com/pkg/errors"
-	"go-im/api/protocol"
 	"sync"
 )
 
+// roomNode is a membership of a channel in a room, a channel is linked in
+// the lists of all the rooms it joined by its nodes.
+type roomNode struct {
+	ch   *Channel
+	room *Room
+	next *roomNode
+	prev *roomNode
+}
+
 type Room struct {
 	Id          string
 	lock        sync.RWMutex
-	next        *Channel //channel链表中的头,新加入的channel统一放头部
-	drop        bool     // make room is live  true表示房间关闭 false表示房间直播中
+	next        *roomNode //channel链表中的头,新加入的channel统一放头部
+	drop        bool      // make room is live  true表示房间关闭 false表示房间直播中
 	Online      int32
 	OnlineCount int32 // room online user count (所有的)
 }
@@ -24,17 +32,17 @@ func NewRoom(roomId string) *Room {
 	}
 }
 
-func (r *Room) Put(ch *Channel) error {
+func (r *Room) Put(n *roomNode) error {
 	r.lock.Lock()
 	defer r.lock.Unlock()
 	if !r.drop {
 		//新的ch会在链表头部
 		if r.next != nil {
-			r.next.Prev = ch
+			r.next.prev = n
 		}
-		ch.Next = r.next
-		r.next = ch
-		ch.Prev = nil
+		n.next = r.next
+		r.next = n
+		n.prev = nil
 		r.Online++
 	} else {
 		return errors.Errorf("room drop id:%s", r.Id)
@@ -42,20 +50,20 @@ func (r *Room) Put(ch *Channel) error {
 
 	return nil
 }
-func (r *Room) Del(ch *Channel) bool {
+func (r *Room) Del(n *roomNode) bool {
 	r.lock.Lock()
-	if ch.Next != nil {
+	if n.next != nil {
 		// if not footer
-		ch.Next.Prev = ch.Prev
+		n.next.prev = n.prev
 	}
-	if ch.Prev != nil {
+	if n.prev != nil {
 		// if not header
-		ch.Prev.Next = ch.Next
+		n.prev.next = n.next
 	} else {
-		r.next = ch.Next
+		r.next = n.next
 	}
-	ch.Next = nil
-	ch.Prev = nil
+	n.next = nil
+	n.prev = nil
 	r.Online--
 	//online等于0 drop为true
 	r.drop = r.Online == 0
@@ -67,9 +75,20 @@ func (r *Room) Close() {
 	r.lock.RLock()
 	defer r.lock.RUnlock()
 	//变量链表 发送停止信号
-	for ch := r.next; ch != nil; ch = ch.Next {
-		ch.Close()
+	for n := r.next; n != nil; n = n.next {
+		n.ch.Close()
+	}
+}
+
+// Channels get the channels in the room, the newest first.
+func (r *Room) Channels() []*Channel {
+	r.lock.RLock()
+	chs := make([]*Channel, 0, r.Online)
+	for n := r.next; n != nil; n = n.next {
+		chs = append(chs, n.ch)
 	}
+	r.lock.RUnlock()
+	return chs
 }
 
 // OnlineNum the room all online.
@@ -81,10 +100,11 @@ func (r *Room) OnlineNum() int32 {
 }
 
 // Push push msg to the room, if chan full discard it.
-func (r *Room) Push(p *protocol.Proto) {
+// The frame is encoded once and shared by all the channels.
+func (r *Room) Push(f *Frame) {
 	r.lock.RLock()
 	defer r.lock.RUnlock()
-	for ch := r.next; ch != nil; ch = ch.Next {
-		ch.Push(p)
+	for n := r.next; n != nil; n = n.next {
+		_ = n.ch.PushFrame(f)
 	}
 }
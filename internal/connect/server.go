@@ -2,19 +2,79 @@ package connect
 
 import (
 	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	pb "go-im/api/connect"
 	"go-im/api/logic"
 	"go-im/internal/connect/conf"
+	"go-im/pkg/certs"
 	"go-im/pkg/cityhash"
+	"go-im/pkg/etcd"
 	"go-im/pkg/log"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+var (
+	// grpc options
+	grpcKeepAliveTime    = time.Duration(10) * time.Second
+	grpcKeepAliveTimeout = time.Duration(3) * time.Second
+	grpcBackoffMaxDelay  = time.Duration(3) * time.Second
+	grpcMaxSendMsgSize   = 1 << 24
+	grpcMaxCallMsgSize   = 1 << 24
+)
+
+const (
+	// grpc options
+	grpcInitialWindowSize     = 1 << 24
+	grpcInitialConnWindowSize = 1 << 24
+
+	_registerTick = time.Second * 10
+)
+
+func newLogicClient(c *conf.RPCClient) logic.LogicClient {
+	ctx, cancel := context.WithTimeout(context.Background(), c.Dial)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, c.Addr,
+		[]grpc.DialOption{
+			grpc.WithInsecure(),
+			grpc.WithInitialWindowSize(grpcInitialWindowSize),
+			grpc.WithInitialConnWindowSize(grpcInitialConnWindowSize),
+			grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(grpcMaxCallMsgSize)),
+			grpc.WithDefaultCallOptions(grpc.MaxCallSendMsgSize(grpcMaxSendMsgSize)),
+			grpc.WithBackoffMaxDelay(grpcBackoffMaxDelay),
+			grpc.WithKeepaliveParams(keepalive.ClientParameters{
+				Time:                grpcKeepAliveTime,
+				Timeout:             grpcKeepAliveTimeout,
+				PermitWithoutStream: true,
+			}),
+		}...,
+	)
+	if err != nil {
+		panic(err)
+	}
+	return logic.NewLogicClient(conn)
+}
+
 type Server struct {
 	c         *conf.Config
 	serverID  string
 	buckets   []*Bucket
 	bucketIdx uint32
 	rpcClient logic.LogicClient
+	versions  map[int32]*version
+	certs     map[string]*certs.Reloader //tls证书, SIGHUP时重新加载
+	certsLock sync.Mutex
+	register  *etcd.Register
+	regStop   chan struct{} //关闭后停止刷新注册的负载
+	regDone   chan struct{} //刷新负载的goroutine已退出
+	drainOnce sync.Once
+	drainHint atomic.Value //[]byte, 排空时下发给客户端的OpReconnect body
+	bans      *banList     //上行超限被临时封禁的ip
 	log       *log.Log
 }
 
@@ -30,13 +90,33 @@ func NewServer(c *conf.Config, serverId string) *Server {
 	s.serverID = serverId
 	s.log = log.NewLog("im", c.Mode.Debug)
 	s.c = c
-	//todo s.rpcClient
+	s.versions = newVersions(c.Protocol.Versions)
+	s.certs = make(map[string]*certs.Reloader)
+	s.bans = newBanList()
+	if err := prometheus.Register(&bucketCollector{buckets: s.buckets}); err != nil {
+		s.log.Error("register bucket metrics err", zap.Error(err))
+	}
+	s.rpcClient = newLogicClient(c.RPCClient)
 
 	//更新用户在线人数
 	go s.onlineProc()
+	if c.Protocol.AckWindow > 0 && c.Protocol.AckTimeout > 0 {
+		go s.resendProc()
+	}
 	return s
 }
 
+// resendProc resend the downstream messages not acked in time.
+func (s *Server) resendProc() {
+	ticker := time.NewTicker(s.c.Protocol.AckTimeout / 2)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		for _, bucket := range s.buckets {
+			bucket.Resend(now)
+		}
+	}
+}
+
 func (s *Server) onlineProc() {
 	for {
 		var (
@@ -49,8 +129,9 @@ func (s *Server) onlineProc() {
 				roomCount[roomID] += count
 			}
 		}
+		connCount, ipCount := s.load()
 		//防止出现死循环
-		if allRoomsCount, err = s.RenewOnline(context.Background(), s.serverID, roomCount); err != nil {
+		if allRoomsCount, err = s.RenewOnline(context.Background(), s.serverID, roomCount, ipCount, connCount); err != nil {
 			time.Sleep(time.Second * 3)
 			continue
 		}
@@ -61,6 +142,76 @@ func (s *Server) onlineProc() {
 	}
 }
 
+// load get the connections and distinct ips of the server.
+func (s *Server) load() (conns, ips int32) {
+	ipSet := make(map[string]struct{})
+	for _, bucket := range s.buckets {
+		conns += int32(bucket.ChannelCount())
+		for ip := range bucket.IPCount() {
+			ipSet[ip] = struct{}{}
+		}
+	}
+	ips = int32(len(ipSet))
+	return
+}
+
+// Instance get the registration of the server with its current load.
+func (s *Server) Instance() *pb.Instance {
+	ins := &pb.Instance{
+		Hostname: s.serverID,
+		Updated:  time.Now().Unix(),
+	}
+	ins.ConnCount, ins.IPCount = s.load()
+	_, port, _ := net.SplitHostPort(s.c.RPCServer.Addr)
+	ins.RPCAddr = net.JoinHostPort(s.serverID, port)
+	if env := s.c.Env; env != nil {
+		ins.Region = env.Region
+		ins.Weight = env.Weight
+		ins.Addrs = env.Addrs
+		ins.Offline = env.Offline
+	}
+	if len(ins.Addrs) == 0 {
+		ins.Addrs = []string{s.serverID}
+	}
+	return ins
+}
+
+// Register register the server to etcd, and refresh its load periodically.
+func (s *Server) Register(c *conf.Discovery) (*etcd.Register, error) {
+	r, err := etcd.NewRegister([]string{c.Host}, pb.DiscoveryPrefix+s.serverID, s.Instance().String(), int64(c.Lease))
+	if err != nil {
+		return nil, err
+	}
+	s.register = r
+	s.regStop = make(chan struct{})
+	s.regDone = make(chan struct{})
+	go func() {
+		defer close(s.regDone)
+		ticker := time.NewTicker(_registerTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.regStop:
+				return
+			case <-ticker.C:
+			}
+			if err := r.Update(s.Instance().String()); err != nil {
+				s.log.Error("update register err", zap.Error(err))
+			}
+		}
+	}()
+	return r, nil
+}
+
+// stopRegister stop refreshing the load and wait the goroutine to exit.
+func (s *Server) stopRegister() {
+	if s.regStop == nil {
+		return
+	}
+	close(s.regStop)
+	<-s.regDone
+}
+
 func (s *Server) Buckets() []*Bucket {
 	return s.buckets
 }
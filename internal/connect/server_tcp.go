@@ -3,13 +3,16 @@ package connect
 import (
 	"bufio"
 	"context"
+	"crypto/tls"
 	"fmt"
 	"go-im/api/protocol"
+	"go-im/pkg/opset"
 	"go-im/pkg/proto"
 	"go.uber.org/zap"
 	"io"
 	"net"
 	"runtime"
+	"sync/atomic"
 	"time"
 )
 
@@ -17,33 +20,52 @@ const (
 	maxInt = 1<<31 - 1
 )
 
+// InitTCP listen the tcp addrs, and the tls addrs if TlsOpen.
 func InitTCP(s *Server, addrs []string) error {
 	for _, addr := range addrs {
-		var (
-			err      error
-			tcpAddr  *net.TCPAddr
-			listener *net.TCPListener
-		)
-		if tcpAddr, err = net.ResolveTCPAddr("tcp", addr); err != nil {
-			s.log.Error("resolve tcp addr err", zap.Error(err))
+		if err := listenTCP(s, addr, nil); err != nil {
 			return err
 		}
-		if listener, err = net.ListenTCP("tcp", tcpAddr); err != nil {
-			s.log.Error("listen tcp err", zap.Error(err))
+	}
+	if c := s.c.Tcp; c.TlsOpen {
+		tlsConf, err := s.TLSConfig(c.CertFile, c.PrivateFile)
+		if err != nil {
 			return err
 		}
-
-		//默认最大go等于cpu核心数
-		for i := 0; i < runtime.NumCPU(); i++ {
-			//分割n核接收连接来提升性能
-			go AcceptTCP(s, listener)
+		for _, addr := range c.TlsBind {
+			if err = listenTCP(s, addr, tlsConf); err != nil {
+				return err
+			}
 		}
+	}
+	return nil
+}
+
+func listenTCP(s *Server, addr string, tlsConf *tls.Config) error {
+	var (
+		err      error
+		tcpAddr  *net.TCPAddr
+		listener *net.TCPListener
+	)
+	if tcpAddr, err = net.ResolveTCPAddr("tcp", addr); err != nil {
+		s.log.Error("resolve tcp addr err", zap.Error(err))
+		return err
+	}
+	if listener, err = net.ListenTCP("tcp", tcpAddr); err != nil {
+		s.log.Error("listen tcp err", zap.Error(err))
+		return err
+	}
 
+	//默认最大go等于cpu核心数
+	for i := 0; i < runtime.NumCPU(); i++ {
+		//分割n核接收连接来提升性能
+		go AcceptTCP(s, listener, tlsConf)
 	}
 	return nil
 }
 
-func AcceptTCP(s *Server, listener *net.TCPListener) {
+// AcceptTCP accept the connections, they are served over tls if tlsConf is not nil.
+func AcceptTCP(s *Server, listener *net.TCPListener, tlsConf *tls.Config) {
 	var (
 		err  error
 		conn *net.TCPConn
@@ -70,20 +92,46 @@ func AcceptTCP(s *Server, listener *net.TCPListener) {
 			return
 		}
 
-		go serverTCP(s, conn, r)
+		if tlsConf != nil {
+			//tls握手在第一次读时进行, 受认证超时的限制
+			go serverTCP(s, tls.Server(conn, tlsConf), r)
+		} else {
+			go serverTCP(s, conn, r)
+		}
 		if r++; r == maxInt {
 			r = 0
 		}
 	}
 }
 
-func serverTCP(s *Server, conn *net.TCPConn, r int) {
+func serverTCP(s *Server, conn net.Conn, r int) {
 	var ch *Channel
-	ch = NewChannel(0, s.c.Protocol.ProtoSize)
+	ch = NewChannel(s.c.Protocol)
 	ch.connTcp = conn
 	s.ServeTCP(ch)
 }
 
+// releaseBody put the pooled body back, the reply still referencing it gets a copy.
+func releaseBody(p *protocol.Proto, buf *proto.Buffer) {
+	if buf == nil {
+		return
+	}
+	if len(p.Body) > 0 && &p.Body[0] == &buf.Bytes()[0] {
+		p.Body = append([]byte(nil), p.Body...)
+	}
+	proto.PutBuffer(buf)
+}
+
+// _closeWait the max time for the writer to write the last reply and close the conn.
+const _closeWait = 5 * time.Second
+
+// waitCloseTCP wait the writer to close the conn after ReplyClose, the
+// client data meanwhile is discarded.
+func waitCloseTCP(ch *Channel, reader *bufio.Reader) {
+	_ = ch.connTcp.SetReadDeadline(time.Now().Add(_closeWait))
+	_, _ = io.Copy(io.Discard, reader)
+}
+
 func (s *Server) closeTCP(ch *Channel, b *Bucket) {
 	ch.connTcp.Close()
 	//todo 处理
@@ -103,115 +151,196 @@ func (s *Server) ServeTCP(ch *Channel) {
 	)
 	reader := bufio.NewReader(ch.connTcp)
 	writer := bufio.NewWriter(ch.connTcp)
+	//ctx跟随连接的生命周期, 读协程退出时取消
 	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
 	//远程连接的ip
 	ch.IP, _, _ = net.SplitHostPort(ch.connTcp.RemoteAddr().String())
+	if s.bans.banned(ch.IP) {
+		cancel()
+		s.closeTCP(ch, b)
+		return
+	}
 	p := new(protocol.Proto)
+	//认证超时则关闭连接
+	if s.c.Protocol.HandshakeTimeout > 0 {
+		_ = ch.connTcp.SetReadDeadline(time.Now().Add(s.c.Protocol.HandshakeTimeout))
+	}
 	//认证tcp连接
-	if ch.Mid, ch.Key, rid, accepts, hb, err = s.authTCP(ctx, reader, writer, p); err != nil {
+	if ch.Mid, ch.Key, rid, accepts, hb, err = s.authTCP(ctx, ch, reader, writer, p); err != nil {
 		s.log.Error("authTCP err:", zap.Error(err))
+		cancel()
 		s.closeTCP(ch, b)
 		return
 	}
-	fmt.Println("heartBeat:", hb)
+	_ = ch.connTcp.SetReadDeadline(time.Time{})
+	//认证后由reaper检查心跳超时
+	ch.hb = hb
+	ch.Touch()
 	ch.Watch(accepts...)
 	//user key=>bucket=>room_id
 	b = s.Bucket(ch.Key)
 	if err = b.Put(rid, ch); err != nil {
 		s.log.Error("put err:", zap.Error(err))
+		cancel()
 		s.closeTCP(ch, b)
+		//logic已经登记了连接, 放入失败(如排空中)时通知logic断开
+		if err = s.Disconnect(context.Background(), ch.Mid, ch.Key); err != nil {
+			s.log.Error(fmt.Sprintf("key: %s mid: %d operator do disconnect", ch.Key, ch.Mid), zap.Error(err))
+		}
 		return
 	}
+	if opset.Of(accepts...).Has(protocol.OpOfflineMsg) {
+		//放入bucket后才能收到logic重放的离线消息
+		go s.Registered(ctx, ch)
+	}
 
 	//读取消息并write数据到客户端
 	go s.writeTCPData(ctx, ch)
 	//读取前端发送过来的消息
-	go s.readTCPData(ctx, ch, b)
+	go func() {
+		s.readTCPData(ctx, ch, b, reader)
+		cancel()
+	}()
 }
 
 func (s *Server) writeTCPData(ctx context.Context, ch *Channel) {
 	var (
 		p      *protocol.Proto
+		f      *Frame
 		finish bool
-		online int32
 		err    error
 	)
-	wr := bufio.NewWriter(ch.connTcp)
 	for {
 		//推送过来的消息
-		p = ch.Ready()
-		fmt.Println("read:", p.Op)
+		p, f = ch.Ready()
 		switch p {
 		case protocol.ProtoFinish:
 			finish = true
 			goto failed
+		case protoClose:
+			//踢出的原因已下发, 关闭连接, 等待读协程结束
+			goto failed
 		case protocol.ProtoReady:
-			if p.Op == protocol.OpHeartbeatReply {
-				if ch.Room != nil {
-					online = ch.Room.OnlineNum()
-				}
-				//读到心跳将房间在线人数返回
-				if err = proto.WriteTCPHeart(p, wr, online); err != nil {
-					goto failed
-				}
-			} else {
-				if err = proto.WriteTcp(p, wr); err != nil {
-					goto failed
-				}
-			}
-			p.Body = nil
+			//只是唤醒写协程, 没有要写的消息
 		default:
 			// server send 如果连接端口，写报错，直接关闭连接
-			if err = proto.WriteTcp(p, wr); err != nil {
+			//头和body一次写出, tcp连接用writev, tls连接合并成一个record
+			if f != nil {
+				//广播消息已编码, 只替换seq
+				codec, threshold := ch.codec()
+				err = f.writeTCP(ch.connTcp, p.Seq, codec, threshold)
+				f.Release()
+			} else {
+				err = proto.WriteTo(ch.connTcp, compressProto(ch, p))
+			}
+			if err != nil {
 				goto failed
 			}
 		}
-		if err = wr.Flush(); err != nil {
-			break
-		}
 	}
 failed:
 	//todo 是否会重复关闭
 	ch.connTcp.Close()
 	// must ensure all channel message discard, for reader won't blocking Signal
 	for !finish {
-		finish = ch.Ready() == protocol.ProtoFinish
+		p, f = ch.Ready()
+		f.Release()
+		finish = p == protocol.ProtoFinish
 	}
 }
 
-func (s *Server) readTCPData(ctx context.Context, ch *Channel, b *Bucket) {
-	var err error
-	reader := bufio.NewReader(ch.connTcp)
+// readTCPData read the client messages, reader must be the one used by auth
+// to keep the buffered data.
+func (s *Server) readTCPData(ctx context.Context, ch *Channel, b *Bucket, reader *bufio.Reader) {
+	var (
+		err    error
+		buf    *proto.Buffer
+		lastHB = time.Now()
+	)
 	for {
 		p := new(protocol.Proto)
-		//消息解析
-		err = proto.ReadTcp(p, reader)
+		//消息解析, body使用池中的内存, 处理完后归还
+		buf, err = proto.ReadTcpBuf(p, reader, ch.ver.MaxBodySize)
 		//todo 处理
 		if err == io.EOF {
 			s.log.Error("io.EOF err:", zap.Error(err))
 		}
+		if err == proto.ErrBodyLen {
+			//body超过版本的限制, 回复错误后关闭连接
+			s.log.Error("read data err:", zap.Int32("op", p.Op), zap.Int32("ver", p.Ver), zap.Error(err))
+			p.Op = protocol.OpError
+			p.Body = errBody(errBodyTooLarge)
+			ch.ReplyClose(p)
+			waitCloseTCP(ch, reader)
+			break
+		}
 		if err != nil {
 			s.log.Error("read data err:", zap.Error(err))
 			break
 		}
-		fmt.Println("p:", p.Op, string(p.Body))
+		ch.Touch()
+		if ok, closing := s.rateLimit(ch, p); !ok {
+			//超限的消息不再处理, 多次超限断开连接
+			releaseBody(p, buf)
+			if closing {
+				ch.ReplyClose(p)
+				waitCloseTCP(ch, reader)
+				break
+			}
+			_ = ch.Reply(p)
+			continue
+		}
+		if err = decompressBody(ch, p); err != nil {
+			s.log.Error("decompress body err:", zap.Int32("op", p.Op), zap.Error(err))
+			p.Op = protocol.OpError
+			if err == proto.ErrBodyLen {
+				//解压后超过版本的限制, 回复错误后关闭连接
+				p.Body = errBody(errBodyTooLarge)
+				releaseBody(p, buf)
+				ch.ReplyClose(p)
+				waitCloseTCP(ch, reader)
+				break
+			}
+			p.Body = errBody(err)
+			releaseBody(p, buf)
+			_ = ch.Reply(p)
+			continue
+		}
 		if p.Op == protocol.OpHeartbeat {
-			p.Op = protocol.OpHeartbeatReply
-			p.Body = nil
-		} else {
+			//节流, 间隔ServerHeartbeat才去logic续期session
+			if now := time.Now(); now.Sub(lastHB) > s.c.Protocol.ServerHeartbeat {
+				revoked, err1 := s.Heartbeat(ctx, ch.Mid, ch.Key)
+				if err1 == nil {
+					lastHB = now
+				}
+				if revoked {
+					//session已被撤销, 通知客户端重新认证
+					p.Op = protocol.OpDisconnectReply
+					p.Body = errBody(errSessionRevoked)
+					ch.ReplyClose(p)
+					waitCloseTCP(ch, reader)
+					break
+				}
+			}
+			heartbeatReply(ch, p)
+		} else if checkOp(ch, p) {
 			if err = s.Operate(ctx, p, b, ch); err != nil {
 				break
 			}
 		}
+		releaseBody(p, buf)
 		//todo 敏感词过滤
 		//channel长度不够会报错，等待数据被发出去
-		if err = ch.Push(p); err != nil {
+		if err = ch.Reply(p); err != nil {
 			s.log.Error(fmt.Sprintf("push proto err, key: %s mid: %d ", ch.Key, ch.Mid), zap.Error(err))
 		}
 	}
 
 	s.closeTCP(ch, b)
+	if atomic.LoadInt32(&ch.drained) == 1 {
+		//排空时已经批量断开
+		return
+	}
 	if err := s.Disconnect(ctx, ch.Mid, ch.Key); err != nil {
 		s.log.Error(fmt.Sprintf("key: %s mid: %d operator do disconnect", ch.Key, ch.Mid), zap.Error(err))
 	}
@@ -219,21 +348,61 @@ func (s *Server) readTCPData(ctx context.Context, ch *Channel, b *Bucket) {
 
 // auth for goim handshake with client, use rsa & aes.
 //返回参数分别: 会员id 唯一key  房间id  用户切换 room, 也就在这里处理  心跳时间
-func (s *Server) authTCP(ctx context.Context, rr *bufio.Reader, wr *bufio.Writer, p *protocol.Proto) (mid int64, key, rid string, accepts []int32, hb time.Duration, err error) {
+func (s *Server) authTCP(ctx context.Context, ch *Channel, rr *bufio.Reader, wr *bufio.Writer, p *protocol.Proto) (mid int64, key, rid string, accepts []int32, hb time.Duration, err error) {
 	for {
 		if err = proto.ReadTcp(p, rr); err != nil {
 			return 0, "", "", nil, 0, err
 		}
+		//握手和认证都会协商协议版本, 不支持的版本回复错误后关闭连接
+		if p.Op == protocol.OpHandshake || p.Op == protocol.OpAuth {
+			if err = s.handshake(ch, p); err != nil {
+				s.log.Error("authTCP.handshake", zap.Int32("ver", p.Ver), zap.Error(err))
+				if proto.WriteTcp(p, wr) == nil {
+					_ = wr.Flush()
+				}
+				return
+			}
+		}
 		//判断是否是认证消息
 		if p.Op == protocol.OpAuth {
 			break
+		} else if p.Op == protocol.OpHandshakeReply {
+			if err = proto.WriteTcp(p, wr); err != nil {
+				return
+			}
+			if err = wr.Flush(); err != nil {
+				return
+			}
 		} else {
 			//todo 是否死循环
 			s.log.Error(fmt.Sprintf("tcp request operation(%d) not auth", p.Op))
 		}
 	}
-	if mid, key, rid, accepts, hb, err = s.Connect(ctx, p, ""); err != nil {
+	if hint, ok := s.Draining(); ok {
+		//排空中不再接受新连接, 通知客户端连接其他节点
+		p.Op = protocol.OpReconnect
+		p.Body = hint
+		if proto.WriteTcp(p, wr) == nil {
+			_ = wr.Flush()
+		}
+		return 0, "", "", nil, 0, errDraining
+	}
+	if err = s.limitIP(ch.IP); err != nil {
+		p.Op = protocol.OpAuthReply
+		p.Body = errBody(err)
+		if proto.WriteTcp(p, wr) == nil {
+			_ = wr.Flush()
+		}
+		return
+	}
+	if mid, key, rid, accepts, ch.policy, hb, err = s.Connect(ctx, p, ""); err != nil {
 		s.log.Error("authTCP.Connect", zap.String("key", key), zap.Error(err))
+		//认证失败, 回复错误原因后关闭连接
+		p.Op = protocol.OpAuthReply
+		p.Body = errBody(err)
+		if proto.WriteTcp(p, wr) == nil {
+			_ = wr.Flush()
+		}
 		return
 	}
 	p.Op = protocol.OpAuthReply
@@ -2,31 +2,93 @@ package connect
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"github.com/gorilla/websocket"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/pkg/errors"
 	"go-im/api/protocol"
+	"go-im/pkg/opset"
+	"go-im/pkg/proto"
 	"go.uber.org/zap"
 	"net"
 	"net/http"
-	"strconv"
+	"sync/atomic"
 	"time"
 )
 
+var errWsMessageType = errors.New("websocket message type not match the subprotocol")
+
+// InitWebsocket listen the websocket addrs, and the wss addrs if TlsOpen.
 func InitWebsocket(s *Server, addrs []string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		handleWs(s, w, r)
+	})
 	for _, addr := range addrs {
-		http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
-			handleWs(s, w, r)
-		})
-		if err := http.ListenAndServe(addr, nil); err != nil {
-			panic(err)
+		if err := listenWs(s, mux, addr, nil); err != nil {
+			return err
+		}
+	}
+	if c := s.c.Websocket; c.TlsOpen {
+		tlsConf, err := s.TLSConfig(c.CertFile, c.PrivateFile)
+		if err != nil {
+			return err
+		}
+		for _, addr := range c.TlsBind {
+			if err = listenWs(s, mux, addr, tlsConf); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
 }
 
+func listenWs(s *Server, handler http.Handler, addr string, tlsConf *tls.Config) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		s.log.Error("listen websocket err", zap.String("addr", addr), zap.Error(err))
+		return err
+	}
+	if tlsConf != nil {
+		lis = tls.NewListener(lis, tlsConf)
+	}
+	go func() {
+		if err := http.Serve(lis, handler); err != nil {
+			s.log.Error("serve websocket err", zap.String("addr", addr), zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+const (
+	// WsProtocolJSON websocket子协议, 文本帧中是json编码的proto
+	WsProtocolJSON = "json"
+	// WsProtocolBinary websocket子协议, 二进制帧中是tcp格式的包
+	WsProtocolBinary = "binary"
+)
+
+// wsSubprotocol select the subprotocol offered by client, binary is preferred.
+// Returns empty if client offers neither.
+func wsSubprotocol(r *http.Request) (subprotocol string, binary bool) {
+	for _, sp := range websocket.Subprotocols(r) {
+		switch sp {
+		case WsProtocolBinary:
+			return sp, true
+		case WsProtocolJSON:
+			subprotocol = sp
+		}
+	}
+	return
+}
+
 func handleWs(s *Server, w http.ResponseWriter, r *http.Request) {
+	subprotocol, binary := wsSubprotocol(r)
+	subprotocols := []string{subprotocol}
+	if subprotocol == "" {
+		//未协商编码的客户端使用json, 原样返回其子协议
+		subprotocols = []string{r.Header.Get("Sec-WebSocket-Protocol")}
+	}
 	var upGrader = websocket.Upgrader{
 		ReadBufferSize:   4096,
 		WriteBufferSize:  1024,
@@ -36,7 +98,7 @@ func handleWs(s *Server, w http.ResponseWriter, r *http.Request) {
 			return true
 		},
 		// 处理 Sec-WebSocket-Protocol Header
-		Subprotocols:      []string{r.Header.Get("Sec-WebSocket-Protocol")},
+		Subprotocols:      subprotocols,
 		EnableCompression: true,
 	}
 
@@ -45,77 +107,153 @@ func handleWs(s *Server, w http.ResponseWriter, r *http.Request) {
 		s.log.Error("upgrade err", zap.Error(err))
 		return
 	}
-	fmt.Println("ws connect")
-	ch := NewChannel(0, s.c.Protocol.ProtoSize)
+	//认证前还未协商版本, 消息大小按默认的限制
+	conn.SetReadLimit(wsReadLimit(binary, proto.MaxBodySize))
+	ch := NewChannel(s.c.Protocol)
 	ch.ws = conn
+	ch.wsBinary = binary
+	//ctx跟随连接的生命周期, 读协程退出时取消
 	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
 	//远程连接的ip
 	ch.IP, _, _ = net.SplitHostPort(ch.ws.RemoteAddr().String())
+	if s.bans.banned(ch.IP) {
+		cancel()
+		s.closeWs(ch, nil)
+		return
+	}
 	var (
 		rid     string
 		accepts []int32
+		hb      time.Duration
 		b       *Bucket
 	)
+	//认证超时则关闭连接
+	if s.c.Protocol.HandshakeTimeout > 0 {
+		_ = conn.SetReadDeadline(time.Now().Add(s.c.Protocol.HandshakeTimeout))
+	}
 	//认证tcp连接
-	if ch.Mid, ch.Key, rid, accepts, _, err = s.authWebsocket(ctx, conn, r.Header.Get("Cookie")); err != nil {
+	if ch.Mid, ch.Key, rid, accepts, hb, err = s.authWebsocket(ctx, ch, r.Header.Get("Cookie")); err != nil {
 		s.log.Error("authTCP err:", zap.Error(err))
+		cancel()
 		s.closeWs(ch, b)
 		return
 	}
+	_ = conn.SetReadDeadline(time.Time{})
+	conn.SetReadLimit(wsReadLimit(binary, ch.ver.MaxBodySize))
+	//认证后由reaper检查心跳超时
+	ch.hb = hb
+	ch.Touch()
 	ch.Watch(accepts...)
 	b = s.Bucket(ch.Key)
 	if err = b.Put(rid, ch); err != nil {
 		s.log.Error("put err:", zap.Error(err))
+		cancel()
 		s.closeWs(ch, b)
+		//logic已经登记了连接, 放入失败(如排空中)时通知logic断开
+		if err = s.Disconnect(context.Background(), ch.Mid, ch.Key); err != nil {
+			s.log.Error(fmt.Sprintf("key: %s mid: %d operator do disconnect", ch.Key, ch.Mid), zap.Error(err))
+		}
 		return
 	}
+	if opset.Of(accepts...).Has(protocol.OpOfflineMsg) {
+		//放入bucket后才能收到logic重放的离线消息
+		go s.Registered(ctx, ch)
+	}
 
 	//读取消息并write数据到客户端
 	go s.writeWs(ctx, ch)
 	//读取前端发送过来的消息
-	go s.readWs(ctx, ch, b)
+	go func() {
+		s.readWs(ctx, ch, b)
+		cancel()
+	}()
 }
 
 func (s *Server) readWs(ctx context.Context, ch *Channel, b *Bucket) {
 	var (
-		err error
-		//mT    int
-		msg []byte
-		p   *protocol.Proto
+		err    error
+		p      *protocol.Proto
+		lastHB = time.Now()
 	)
 	for {
 		p = new(protocol.Proto)
-		if _, msg, err = ch.ws.ReadMessage(); err != nil {
+		if err = readWsProto(ch.ws, ch.wsBinary, p); err != nil {
 			//检测到前端关闭
-			if !websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNoStatusReceived, websocket.CloseAbnormalClosure) {
+			if _, ok := err.(*websocket.CloseError); ok && !websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNoStatusReceived, websocket.CloseAbnormalClosure) {
 				break
 			}
 			s.log.Error("ws read data err", zap.Error(err))
 			break
 		}
 
-		if err = jsoniter.Unmarshal(msg, p); err != nil {
-			s.log.Error("Unmarshal data err", zap.String("data", string(msg)), zap.Error(err))
+		ch.Touch()
+		if len(p.Body) > int(ch.ver.MaxBodySize) {
+			//body超过版本的限制, 回复错误后关闭连接
+			s.log.Error("ws read data err", zap.Int32("op", p.Op), zap.Error(proto.ErrBodyLen))
+			p.Op = protocol.OpError
+			p.Body = errBody(errBodyTooLarge)
+			ch.ReplyClose(p)
+			waitCloseWs(ch)
 			break
 		}
+		if ok, closing := s.rateLimit(ch, p); !ok {
+			//超限的消息不再处理, 多次超限断开连接
+			if closing {
+				ch.ReplyClose(p)
+				waitCloseWs(ch)
+				break
+			}
+			_ = ch.Reply(p)
+			continue
+		}
+		if err = decompressBody(ch, p); err != nil {
+			s.log.Error("ws decompress body err", zap.Int32("op", p.Op), zap.Error(err))
+			p.Op = protocol.OpError
+			if err == proto.ErrBodyLen {
+				//解压后超过版本的限制, 回复错误后关闭连接
+				p.Body = errBody(errBodyTooLarge)
+				ch.ReplyClose(p)
+				waitCloseWs(ch)
+				break
+			}
+			p.Body = errBody(err)
+			_ = ch.Reply(p)
+			continue
+		}
 
-		fmt.Println("p:", p.Op, string(p.Body))
 		if p.Op == protocol.OpHeartbeat {
-			p.Op = protocol.OpHeartbeatReply
-			p.Body = nil
-		} else {
+			//节流, 间隔ServerHeartbeat才去logic续期session
+			if now := time.Now(); now.Sub(lastHB) > s.c.Protocol.ServerHeartbeat {
+				revoked, err1 := s.Heartbeat(ctx, ch.Mid, ch.Key)
+				if err1 == nil {
+					lastHB = now
+				}
+				if revoked {
+					//session已被撤销, 通知客户端重新认证
+					p.Op = protocol.OpDisconnectReply
+					p.Body = errBody(errSessionRevoked)
+					ch.ReplyClose(p)
+					waitCloseWs(ch)
+					break
+				}
+			}
+			heartbeatReply(ch, p)
+		} else if checkOp(ch, p) {
 			if err = s.Operate(ctx, p, b, ch); err != nil {
 				break
 			}
 		}
 		//channel长度不够会报错，等待数据被发出去
-		if err = ch.Push(p); err != nil {
+		if err = ch.Reply(p); err != nil {
 			s.log.Error(fmt.Sprintf("push proto err, key: %s mid: %d ", ch.Key, ch.Mid), zap.Error(err))
 		}
 	}
 
-	s.closeTCP(ch, b)
+	s.closeWs(ch, b)
+	if atomic.LoadInt32(&ch.drained) == 1 {
+		//排空时已经批量断开
+		return
+	}
 	if err := s.Disconnect(ctx, ch.Mid, ch.Key); err != nil {
 		s.log.Error(fmt.Sprintf("key: %s mid: %d operator do disconnect", ch.Key, ch.Mid), zap.Error(err))
 	}
@@ -124,74 +262,116 @@ func (s *Server) readWs(ctx context.Context, ch *Channel, b *Bucket) {
 func (s *Server) writeWs(ctx context.Context, ch *Channel) {
 	var (
 		p      *protocol.Proto
+		f      *Frame
 		finish bool
 		err    error
 	)
 	for {
 		//推送过来的消息
-		p = ch.Ready()
-		fmt.Println("read:", p.Op)
+		p, f = ch.Ready()
 		switch p {
 		case protocol.ProtoFinish:
 			finish = true
 			goto failed
+		case protoClose:
+			//踢出的原因已下发, 关闭连接, 等待读协程结束
+			goto failed
 		case protocol.ProtoReady:
-			if p.Op == protocol.OpHeartbeatReply {
-				if ch.Room != nil {
-					p.Body = []byte(strconv.Itoa(int(ch.Room.OnlineNum())))
-				}
+			//只是唤醒写协程, 没有要写的消息
+		default:
+			if f != nil {
+				//广播消息已编码, 只替换seq
+				err = writeWsFrame(ch, f, p.Seq)
+				f.Release()
 			} else {
-				p.Body = nil
-			}
-			if err = s.write(ch.ws, p); err != nil {
-				goto failed
+				err = writeWsProto(ch.ws, ch.wsBinary, compressProto(ch, p))
 			}
-		default:
-			if err = s.write(ch.ws, p); err != nil {
+			if err != nil {
 				goto failed
 			}
 		}
 	}
 failed:
 	//todo 是否会重复关闭
-	ch.connTcp.Close()
+	_ = ch.CloseConn()
 	// must ensure all channel message discard, for reader won't blocking Signal
 	for !finish {
-		finish = ch.Ready() == protocol.ProtoFinish
+		p, f = ch.Ready()
+		f.Release()
+		finish = p == protocol.ProtoFinish
 	}
 }
 
-func (s *Server) write(ws *websocket.Conn, p *protocol.Proto) error {
-	var (
-		err error
-		msg []byte
-	)
+// writeWsProto write a proto in a websocket message, binary message uses the tcp package format.
+func writeWsProto(ws *websocket.Conn, binary bool, p *protocol.Proto) error {
+	if binary {
+		return ws.WriteMessage(websocket.BinaryMessage, proto.Encode(p))
+	}
+	msg, err := jsoniter.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return ws.WriteMessage(websocket.TextMessage, msg)
+}
 
-	if msg, err = jsoniter.Marshal(p); err != nil {
+// writeWsFrame write a pre-encoded broadcast frame with the seq of the channel.
+func writeWsFrame(ch *Channel, f *Frame, seq int32) error {
+	mt := websocket.TextMessage
+	if ch.wsBinary {
+		mt = websocket.BinaryMessage
+	}
+	w, err := ch.ws.NextWriter(mt)
+	if err != nil {
 		return err
 	}
-	if err = ws.WriteMessage(websocket.TextMessage, msg); err != nil {
+	if ch.wsBinary {
+		codec, threshold := ch.codec()
+		err = f.writeTCP(w, seq, codec, threshold)
+	} else {
+		err = f.writeJSON(w, seq)
+	}
+	if err != nil {
+		_ = w.Close()
 		return err
 	}
-
-	return nil
+	return w.Close()
 }
 
-func (s *Server) read(ws *websocket.Conn, p *protocol.Proto) error {
-	var (
-		err error
-		msg []byte
-	)
-
-	if _, msg, err = ws.ReadMessage(); err != nil {
-		return nil
+// readWsProto read a proto from a websocket message, the message type must match the subprotocol.
+func readWsProto(ws *websocket.Conn, binary bool, p *protocol.Proto) error {
+	mt, msg, err := ws.ReadMessage()
+	if err != nil {
+		return err
+	}
+	if binary {
+		if mt != websocket.BinaryMessage {
+			return errWsMessageType
+		}
+		return proto.Decode(msg, p)
 	}
+	if mt != websocket.TextMessage {
+		return errWsMessageType
+	}
+	return jsoniter.Unmarshal(msg, p)
+}
 
-	if err = jsoniter.Unmarshal(msg, p); err != nil {
-		return nil
+// wsReadLimit the max message size of the version, the json body is base64 encoded.
+func wsReadLimit(binary bool, maxBody int32) int64 {
+	if binary {
+		return int64(proto.RawHeaderSize + maxBody)
 	}
+	return int64(maxBody)*4/3 + 1024
+}
 
-	return nil
+// waitCloseWs wait the writer to close the conn after ReplyClose, the
+// client messages meanwhile are discarded.
+func waitCloseWs(ch *Channel) {
+	_ = ch.ws.SetReadDeadline(time.Now().Add(_closeWait))
+	for {
+		if _, _, err := ch.ws.NextReader(); err != nil {
+			return
+		}
+	}
 }
 
 func (s *Server) closeWs(ch *Channel, b *Bucket) {
@@ -203,43 +383,69 @@ func (s *Server) closeWs(ch *Channel, b *Bucket) {
 }
 
 // auth for goim handshake with client, use rsa & aes.
-func (s *Server) authWebsocket(ctx context.Context, ws *websocket.Conn, cookie string) (mid int64, key, rid string, accepts []int32, hb time.Duration, err error) {
+func (s *Server) authWebsocket(ctx context.Context, ch *Channel, cookie string) (mid int64, key, rid string, accepts []int32, hb time.Duration, err error) {
 	var (
-		mT    int
-		msg   []byte
-		times = 0
+		times  = 0
+		ws     = ch.ws
+		binary = ch.wsBinary
 	)
 	p := new(protocol.Proto)
 	for {
 		times++
 		if times >= 4 {
-			s.log.Warn(fmt.Sprintf("超过3次认证失败, cookie:%s", cookie))
+			s.log.Warn("超过3次认证失败", zap.String("ip", ch.IP))
 			err = errors.New("超过3次认证失败")
 			return
 		}
-		if mT, msg, err = ws.ReadMessage(); err != nil {
-			s.log.Error("ws read data err", zap.Error(err))
+		if err = readWsProto(ws, binary, p); err != nil {
+			s.log.Error("ws read auth err", zap.Error(err))
 			return
 		}
-
-		if err = jsoniter.Unmarshal(msg, p); err != nil {
-			s.log.Error("Unmarshal data err", zap.String("data", string(msg)), zap.Error(err))
-			return
+		//握手和认证都会协商协议版本, 不支持的版本回复错误后关闭连接
+		if p.Op == protocol.OpHandshake || p.Op == protocol.OpAuth {
+			if err = s.handshake(ch, p); err != nil {
+				s.log.Error("authWebsocket.handshake", zap.Int32("ver", p.Ver), zap.Error(err))
+				_ = writeWsProto(ws, binary, p)
+				return
+			}
 		}
-
-		if p.Op == protocol.OpAuth && mT == websocket.TextMessage {
+		if p.Op == protocol.OpAuth {
 			break
+		} else if p.Op == protocol.OpHandshakeReply {
+			//握手同样计入次数, 避免无限握手
+			if err = writeWsProto(ws, binary, p); err != nil {
+				return
+			}
 		} else {
-			s.log.Error("ws request operation not auth", zap.Error(err))
+			s.log.Error("ws request operation not auth", zap.Int32("op", p.Op))
 		}
 	}
-	if mid, key, rid, accepts, hb, err = s.Connect(ctx, p, cookie); err != nil {
+	if hint, ok := s.Draining(); ok {
+		//排空中不再接受新连接, 通知客户端连接其他节点
+		p.Op = protocol.OpReconnect
+		p.Body = hint
+		_ = writeWsProto(ws, binary, p)
+		err = errDraining
+		return
+	}
+	if err = s.limitIP(ch.IP); err != nil {
+		p.Op = protocol.OpAuthReply
+		p.Body = errBody(err)
+		_ = writeWsProto(ws, binary, p)
+		return
+	}
+	if mid, key, rid, accepts, ch.policy, hb, err = s.Connect(ctx, p, cookie); err != nil {
+		s.log.Error("authWebsocket.Connect", zap.String("ip", ch.IP), zap.Error(err))
+		//认证失败, 回复错误原因后关闭连接
+		p.Op = protocol.OpAuthReply
+		p.Body = errBody(err)
+		_ = writeWsProto(ws, binary, p)
 		return
 	}
 	p.Op = protocol.OpAuthReply
 	p.Body = nil
 
-	if err = s.write(ws, p); err != nil {
+	if err = writeWsProto(ws, binary, p); err != nil {
 		s.log.Error("ws write auth reply ", zap.Error(err))
 		return
 	}
@@ -0,0 +1,117 @@
+package connect
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"go-im/api/protocol"
+	"go-im/internal/connect/conf"
+	"go-im/pkg/log"
+	"go.uber.org/zap"
+)
+
+// echo the protos in the negotiated subprotocol.
+func wsEchoServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		subprotocol, binary := wsSubprotocol(r)
+		upGrader := websocket.Upgrader{Subprotocols: []string{subprotocol}}
+		conn, err := upGrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		for {
+			p := new(protocol.Proto)
+			if err := readWsProto(conn, binary, p); err != nil {
+				return
+			}
+			if err := writeWsProto(conn, binary, p); err != nil {
+				return
+			}
+		}
+	}))
+}
+
+func TestWsProtoRoundTrip(t *testing.T) {
+	srv := wsEchoServer(t)
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+	for _, c := range []struct {
+		offer  []string
+		want   string
+		binary bool
+	}{
+		{offer: []string{WsProtocolBinary, WsProtocolJSON}, want: WsProtocolBinary, binary: true},
+		{offer: []string{WsProtocolJSON, WsProtocolBinary}, want: WsProtocolBinary, binary: true},
+		{offer: []string{WsProtocolJSON}, want: WsProtocolJSON},
+	} {
+		conn, _, err := (&websocket.Dialer{Subprotocols: c.offer}).Dial(url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if conn.Subprotocol() != c.want {
+			t.Fatalf("offer %v negotiated %q want %q", c.offer, conn.Subprotocol(), c.want)
+		}
+		p := &protocol.Proto{Ver: 1, Op: protocol.OpSendMsg, Seq: 9, Body: []byte(`{"msg":"hello"}`)}
+		if err = writeWsProto(conn, c.binary, p); err != nil {
+			t.Fatal(err)
+		}
+		got := new(protocol.Proto)
+		if err = readWsProto(conn, c.binary, got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Ver != p.Ver || got.Op != p.Op || got.Seq != p.Seq || !bytes.Equal(got.Body, p.Body) {
+			t.Fatalf("got %v want %v", got, p)
+		}
+		// the message type must match the subprotocol
+		if err = writeWsProto(conn, !c.binary, p); err != nil {
+			t.Fatal(err)
+		}
+		if err = readWsProto(conn, c.binary, got); err == nil {
+			t.Fatal("mismatched message type should close the conn")
+		}
+		conn.Close()
+	}
+}
+
+func TestWsAuthHandshakeLimit(t *testing.T) {
+	s := &Server{log: &log.Log{Logger: zap.NewNop()}}
+	errs := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		ch := NewChannel(&conf.Protocol{})
+		ch.ws = conn
+		_, _, _, _, _, err = s.authWebsocket(context.Background(), ch, "")
+		errs <- err
+	}))
+	defer srv.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// the handshakes are counted, the client can't handshake forever
+	for i := 0; i < 3; i++ {
+		p := &protocol.Proto{Ver: 1, Op: protocol.OpHandshake}
+		if err = writeWsProto(conn, false, p); err != nil {
+			t.Fatal(err)
+		}
+		if err = readWsProto(conn, false, p); err != nil || p.Op != protocol.OpHandshakeReply {
+			t.Fatalf("handshake %d op %d %v", i, p.Op, err)
+		}
+	}
+	if err = <-errs; err == nil {
+		t.Fatal("handshake not limited")
+	}
+}
@@ -0,0 +1,41 @@
+package connect
+
+import (
+	"crypto/tls"
+	"go-im/pkg/certs"
+	"go.uber.org/zap"
+)
+
+// TLSConfig get the server tls config of the key pair, the certificate is
+// reloaded by ReloadCerts.
+func (s *Server) TLSConfig(certFile, keyFile string) (*tls.Config, error) {
+	s.certsLock.Lock()
+	defer s.certsLock.Unlock()
+	key := certFile + ":" + keyFile
+	r, ok := s.certs[key]
+	if !ok {
+		var err error
+		if r, err = certs.NewReloader(certFile, keyFile); err != nil {
+			s.log.Error("load certificate err", zap.String("cert", certFile), zap.Error(err))
+			return nil, err
+		}
+		s.certs[key] = r
+	}
+	return &tls.Config{
+		GetCertificate: r.GetCertificate,
+		MinVersion:     tls.VersionTLS12,
+	}, nil
+}
+
+// ReloadCerts reload all the certificates, the old one is kept if failed.
+func (s *Server) ReloadCerts() {
+	s.certsLock.Lock()
+	defer s.certsLock.Unlock()
+	for key, r := range s.certs {
+		if err := r.Reload(); err != nil {
+			s.log.Error("reload certificate err", zap.String("key", key), zap.Error(err))
+			continue
+		}
+		s.log.Info("reload certificate", zap.String("key", key))
+	}
+}
@@ -0,0 +1,126 @@
+package connect
+
+import (
+	"fmt"
+
+	jsoniter "github.com/json-iterator/go"
+	"go-im/api/protocol"
+	"go-im/internal/connect/conf"
+	"go-im/pkg/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// errBodyTooLarge the upstream body exceeds the limit of the version.
+	errBodyTooLarge = status.Error(codes.ResourceExhausted, "body too large")
+	// errOpNotAllowed the upstream op is not allowed by the version.
+	errOpNotAllowed = status.Error(codes.PermissionDenied, "operation not allowed")
+)
+
+// _anyVersion is used when no versions configured, any version is accepted.
+var _anyVersion = newVersion(&conf.Version{MaxBodySize: proto.MaxBodySize})
+
+// version is a supported protocol version.
+type version struct {
+	*conf.Version
+	ops   map[int32]struct{}
+	codec proto.Codec
+}
+
+func newVersion(c *conf.Version) *version {
+	v := &version{Version: c}
+	if v.MaxBodySize <= 0 {
+		v.MaxBodySize = proto.MaxBodySize
+	}
+	codec, err := proto.ParseCodec(c.Compress)
+	if err != nil {
+		panic(fmt.Sprintf("version %d compress %q: %v", c.Ver, c.Compress, err))
+	}
+	v.codec = codec
+	if len(c.Ops) > 0 {
+		v.ops = make(map[int32]struct{}, len(c.Ops)+1)
+		for _, op := range c.Ops {
+			v.ops[op] = struct{}{}
+		}
+		v.ops[protocol.OpHeartbeat] = struct{}{}
+	}
+	return v
+}
+
+// allow reports whether the upstream op is allowed.
+func (v *version) allow(op int32) bool {
+	if v.ops == nil {
+		return true
+	}
+	_, ok := v.ops[op]
+	return ok
+}
+
+// handshakeBody the limits replied by OpHandshakeReply.
+func (v *version) handshakeBody(ver int32) []byte {
+	b, _ := jsoniter.Marshal(&struct {
+		Ver               int32   `json:"ver"`
+		MaxBodySize       int32   `json:"max_body_size"`
+		Ops               []int32 `json:"ops,omitempty"`
+		Compress          string  `json:"compress,omitempty"`
+		CompressThreshold int32   `json:"compress_threshold,omitempty"`
+	}{
+		Ver:               ver,
+		MaxBodySize:       v.MaxBodySize,
+		Ops:               v.Ops,
+		Compress:          v.Compress,
+		CompressThreshold: v.CompressThreshold,
+	})
+	return b
+}
+
+func newVersions(c []*conf.Version) map[int32]*version {
+	vs := make(map[int32]*version, len(c))
+	for _, vc := range c {
+		vs[vc.Ver] = newVersion(vc)
+	}
+	return vs
+}
+
+// version get the supported version, returns error if not supported.
+func (s *Server) version(ver int32) (*version, error) {
+	if len(s.versions) == 0 {
+		return _anyVersion, nil
+	}
+	if v, ok := s.versions[ver]; ok {
+		return v, nil
+	}
+	vers := make([]int32, 0, len(s.versions))
+	for v := range s.versions {
+		vers = append(vers, v)
+	}
+	return nil, status.Error(codes.FailedPrecondition, fmt.Sprintf("unsupported protocol version %d, supported %v", ver, vers))
+}
+
+// handshake negotiate the version by the client's OpHandshake or OpAuth.
+// The reply is OpHandshakeReply with the limits, or OpError if not supported.
+func (s *Server) handshake(ch *Channel, p *protocol.Proto) (err error) {
+	//版本号的高位是body的压缩算法
+	p.Ver = proto.BaseVer(p.Ver)
+	if ch.ver, err = s.version(p.Ver); err != nil {
+		p.Op = protocol.OpError
+		p.Body = errBody(err)
+		return
+	}
+	if p.Op == protocol.OpHandshake {
+		p.Op = protocol.OpHandshakeReply
+		p.Body = ch.ver.handshakeBody(p.Ver)
+	}
+	return
+}
+
+// checkOp reject the upstream op not allowed by the version, p is set to the OpError reply.
+func checkOp(ch *Channel, p *protocol.Proto) bool {
+	if ch.ver.allow(p.Op) {
+		return true
+	}
+	p.Op = protocol.OpError
+	p.Body = errBody(errOpNotAllowed)
+	return false
+}
@@ -0,0 +1,53 @@
+package connect
+
+import (
+	"testing"
+
+	"go-im/api/protocol"
+	"go-im/internal/connect/conf"
+	"go-im/pkg/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestVersion(t *testing.T) {
+	s := &Server{versions: newVersions([]*conf.Version{
+		{Ver: 1, MaxBodySize: 1024, Ops: []int32{protocol.OpSendMsg}},
+		{Ver: 2},
+	})}
+	v, err := s.version(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.MaxBodySize != 1024 {
+		t.Fatalf("max body %d", v.MaxBodySize)
+	}
+	if !v.allow(protocol.OpSendMsg) || !v.allow(protocol.OpHeartbeat) || v.allow(protocol.OpChangeRoom) {
+		t.Fatal("version 1 ops")
+	}
+	if v, _ = s.version(2); v.MaxBodySize != proto.MaxBodySize || !v.allow(protocol.OpChangeRoom) {
+		t.Fatal("version 2 defaults")
+	}
+	if _, err = s.version(3); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("version 3 err %v", err)
+	}
+
+	ch := new(Channel)
+	p := &protocol.Proto{Ver: 3, Op: protocol.OpHandshake}
+	if err = s.handshake(ch, p); err == nil || p.Op != protocol.OpError {
+		t.Fatalf("handshake unsupported op %d err %v", p.Op, err)
+	}
+	p = &protocol.Proto{Ver: 1, Op: protocol.OpHandshake}
+	if err = s.handshake(ch, p); err != nil || p.Op != protocol.OpHandshakeReply {
+		t.Fatalf("handshake op %d err %v", p.Op, err)
+	}
+	p = &protocol.Proto{Ver: 1, Op: protocol.OpChangeRoom, Seq: 7}
+	if checkOp(ch, p) || p.Op != protocol.OpError || p.Seq != 7 {
+		t.Fatalf("checkOp %+v", p)
+	}
+
+	//未配置版本时不限制
+	if v, err = new(Server).version(9); err != nil || v != _anyVersion {
+		t.Fatalf("any version %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package connect
+
+import (
+	"sync"
+	"time"
+
+	"go-im/api/protocol"
+)
+
+// window keeps the downstream messages which were not acked by the client.
+// Messages are ordered by seq, and resent after timeout or when the client
+// reconnects with its last seen seq.
+type window struct {
+	lock    sync.Mutex
+	seq     int32 // last assigned seq
+	size    int
+	timeout time.Duration
+	resume  time.Duration
+	pending []*pending
+	expire  time.Time // deadline to be resumed by a new channel after the old one closed
+}
+
+type pending struct {
+	p    *protocol.Proto
+	f    *Frame // 广播消息共享的编码, 确认后释放
+	sent time.Time
+}
+
+func newWindow(size int, timeout, resume time.Duration) *window {
+	return &window{
+		size:    size,
+		timeout: timeout,
+		resume:  resume,
+	}
+}
+
+// add assign a seq to the message, returns the sequenced copy.
+// The oldest message is evicted if the window is full, so the clients which
+// never ack still receive the new messages. The frame is retained until acked.
+func (w *window) add(p *protocol.Proto, f *Frame) *protocol.Proto {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.size > 0 && len(w.pending) >= w.size {
+		//窗口已满, 淘汰最早的消息, 不再重传
+		w.pending[0].f.Release()
+		w.pending[0] = nil
+		w.pending = w.pending[1:]
+	}
+	//同一个proto会推送给多个channel, 需要复制一份再设置seq
+	w.seq++
+	np := &protocol.Proto{
+		Ver:  p.Ver,
+		Op:   p.Op,
+		Seq:  w.seq,
+		Body: p.Body,
+	}
+	if w.size > 0 {
+		f.Retain()
+		w.pending = append(w.pending, &pending{p: np, f: f, sent: time.Now()})
+	}
+	return np
+}
+
+// ack remove all messages whose seq <= the acked seq.
+func (w *window) ack(seq int32) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	i := 0
+	for ; i < len(w.pending); i++ {
+		if w.pending[i].p.Seq > seq {
+			break
+		}
+		w.pending[i].f.Release()
+	}
+	w.pending = w.pending[i:]
+}
+
+// expired get the messages need to be resent, force means resend all.
+// The frames are retained for the caller, in case they're acked meanwhile.
+func (w *window) expired(now time.Time, force bool) (ps []pending) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for _, pd := range w.pending {
+		if force || now.Sub(pd.sent) >= w.timeout {
+			pd.sent = now
+			pd.f.Retain()
+			ps = append(ps, *pd)
+		}
+	}
+	return
+}
+
+// detach mark the window waiting for resume after its channel closed.
+// Returns false if there is nothing to resume.
+func (w *window) detach(now time.Time) bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.resume <= 0 || len(w.pending) == 0 {
+		return false
+	}
+	w.expire = now.Add(w.resume)
+	return true
+}
+
+// alive reports whether the detached window can still be resumed.
+func (w *window) alive(now time.Time) bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return now.Before(w.expire)
+}
@@ -0,0 +1,102 @@
+package connect
+
+import (
+	"testing"
+	"time"
+
+	"go-im/api/protocol"
+	"go-im/internal/connect/conf"
+)
+
+func TestWindow(t *testing.T) {
+	w := newWindow(2, time.Second, time.Minute)
+	p := &protocol.Proto{Ver: 1, Op: 1000, Body: []byte("a")}
+	for i := int32(1); i <= 3; i++ {
+		if np := w.add(p, nil); np.Seq != i || np == p {
+			t.Fatalf("add seq %d want %d", np.Seq, i)
+		}
+	}
+	// the oldest is evicted when full
+	if len(w.pending) != 2 || w.pending[0].p.Seq != 2 {
+		t.Fatalf("pending %d first %d", len(w.pending), w.pending[0].p.Seq)
+	}
+
+	now := time.Now()
+	if ps := w.expired(now, false); len(ps) != 0 {
+		t.Fatalf("expired before timeout %d", len(ps))
+	}
+	if ps := w.expired(now.Add(time.Second), false); len(ps) != 2 || ps[0].p.Seq != 2 {
+		t.Fatalf("expired %d", len(ps))
+	}
+	// the resent ones wait for another timeout
+	if ps := w.expired(now.Add(time.Second), false); len(ps) != 0 {
+		t.Fatalf("expired again %d", len(ps))
+	}
+
+	w.ack(2)
+	if len(w.pending) != 1 || w.pending[0].p.Seq != 3 {
+		t.Fatalf("ack pending %d", len(w.pending))
+	}
+	if !w.detach(now) || !w.alive(now.Add(time.Second)) || w.alive(now.Add(time.Minute)) {
+		t.Fatal("detach")
+	}
+	w.ack(3)
+	if w.detach(now) {
+		t.Fatal("detach empty window")
+	}
+
+	// not sequenced into window without size
+	w = newWindow(0, time.Second, time.Minute)
+	if np := w.add(p, nil); np.Seq != 1 || len(w.pending) != 0 {
+		t.Fatalf("no window seq %d pending %d", np.Seq, len(w.pending))
+	}
+}
+
+func TestWindowResume(t *testing.T) {
+	b := newTestBucket(t)
+	c := &conf.Protocol{AckWindow: 8, AckTimeout: time.Minute, ResumeTimeout: time.Minute}
+	old := NewChannel(c)
+	old.Key = "a"
+	if err := b.Put("", old); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := old.Push(&protocol.Proto{Ver: 1, Op: 1000}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old.Ack(1)
+	b.Del(old)
+
+	// reconnected, the unacked messages are resent after the client reports its seq
+	ch := NewChannel(c)
+	ch.Key = "a"
+	if err := b.Put("", ch); err != nil {
+		t.Fatal(err)
+	}
+	ch.Ack(2)
+	if p, _ := ch.Ready(); p.Seq != 3 {
+		t.Fatalf("resent seq %d", p.Seq)
+	}
+	if err := ch.Push(&protocol.Proto{Ver: 1, Op: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := ch.Ready(); p.Seq != 4 {
+		t.Fatalf("seq not continued %d", p.Seq)
+	}
+
+	// another mid connecting with the same key must not take over the messages
+	ch.Mid = 1
+	b.Del(ch)
+	other := NewChannel(c)
+	other.Key, other.Mid = "a", 2
+	if err := b.Put("", other); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Push(&protocol.Proto{Ver: 1, Op: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := other.Ready(); p.Seq != 1 {
+		t.Fatalf("window taken over seq %d", p.Seq)
+	}
+}
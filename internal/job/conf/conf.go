@@ -2,6 +2,7 @@ package conf
 
 import (
 	"github.com/spf13/viper"
+	"go-im/pkg/tracing"
 	"strings"
 )
 
@@ -33,6 +34,25 @@ type Config struct {
 	Mode      *Mode
 	Discovery *Discovery
 	Kafka     *Kafka
+	Comet     *Comet
+	Metrics   *Metrics
+	Tracing   *tracing.Config
+}
+
+// Metrics is the prometheus metrics endpoint config.
+type Metrics struct {
+	Addr string
+}
+
+// Comet is comet client config.
+type Comet struct {
+	RoutineSize int    //每个comet推送协程数量
+	RoutineChan int    //每个推送协程的channel长度
+	TlsOpen     bool   //通过mTLS连接comet的grpc
+	CertFile    string //客户端证书
+	PrivateFile string
+	CAFile      string //校验comet证书的CA
+	ServerName  string //comet证书的域名, 为空时使用comet的地址
 }
 
 type Kafka struct {
@@ -42,9 +62,9 @@ type Kafka struct {
 }
 
 type Discovery struct {
-	driver  string
-	host    string
-	timeout int
+	Driver  string
+	Host    string
+	Timeout int
 }
 
 type Websocket struct {
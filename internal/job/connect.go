@@ -2,14 +2,19 @@ package job
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"github.com/pkg/errors"
 	"go-im/api/connect"
 	"go-im/internal/job/conf"
+	"go-im/pkg/certs"
 	"go-im/pkg/log"
+	"go-im/pkg/tracing"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/keepalive"
+	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -31,50 +36,106 @@ const (
 
 type ConnectServer struct {
 	serverId      string
+	addr          string
+	conn          *grpc.ClientConn
 	client        connect.CometClient
-	pushCh        []chan *connect.PushMsgReq
-	roomCh        []chan *connect.BroadcastRoomReq
-	broadcastChan chan *connect.BroadcastReq
+	pushCh        []chan keysMsg
+	roomCh        []chan roomMsg
+	broadcastChan chan broadcastMsg
 
 	pushChanNum uint64
 	roomChanNum uint64
 	routineSize uint64
 
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
 	log *log.Log
 }
 
-func newConnectServer(c *conf.Config) (*ConnectServer, error) {
+// keysMsg, roomMsg and broadcastMsg carry the trace context of the kafka
+// message to the push routines.
+type keysMsg struct {
+	ctx context.Context
+	req *connect.PushMsgReq
+}
+
+type roomMsg struct {
+	ctx context.Context
+	req *connect.BroadcastRoomReq
+}
+
+type broadcastMsg struct {
+	ctx context.Context
+	req *connect.BroadcastReq
+}
+
+func newConnectServer(c *conf.Comet, creds credentials.TransportCredentials, serverId, addr string, l *log.Log) (*ConnectServer, error) {
 	s := new(ConnectServer)
-	s.serverId = "connect_server_1"
-	//
-	client, err := newCometClient("127.0.0.1:5566")
+	s.serverId = serverId
+	s.addr = addr
+	s.log = l
+	conn, err := newCometClient(addr, creds)
 	if err != nil {
 		return nil, err
 	}
-	s.client = client
-	s.pushCh = make([]chan *connect.PushMsgReq, s.routineSize)
-	s.roomCh = make([]chan *connect.BroadcastRoomReq, s.routineSize)
-	s.broadcastChan = make(chan *connect.BroadcastReq, s.routineSize)
+	s.conn = conn
+	s.client = connect.NewCometClient(conn)
+	s.routineSize = uint64(c.RoutineSize)
+	s.pushCh = make([]chan keysMsg, s.routineSize)
+	s.roomCh = make([]chan roomMsg, s.routineSize)
+	s.broadcastChan = make(chan broadcastMsg, s.routineSize)
+	s.ctx, s.cancel = context.WithCancel(context.Background())
 	for i := 0; i < int(s.routineSize); i++ {
-		s.pushCh[i] = make(chan *connect.PushMsgReq, s.pushChanNum)
-		s.roomCh[i] = make(chan *connect.BroadcastRoomReq, s.roomChanNum)
+		s.pushCh[i] = make(chan keysMsg, c.RoutineChan)
+		s.roomCh[i] = make(chan roomMsg, c.RoutineChan)
+		s.wg.Add(1)
 		go s.process(s.pushCh[i], s.roomCh[i], s.broadcastChan)
 	}
 
 	return s, nil
 }
 
-func newCometClient(addr string) (connect.CometClient, error) {
+// newCometCreds get the mTLS credentials of the comet clients, the client
+// certificate is reloaded by the returned reloader. Returns nil if not TlsOpen.
+func newCometCreds(c *conf.Comet) (credentials.TransportCredentials, *certs.Reloader, error) {
+	if !c.TlsOpen {
+		return nil, nil, nil
+	}
+	cert, err := certs.NewReloader(c.CertFile, c.PrivateFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	tlsConf := &tls.Config{
+		GetClientCertificate: cert.GetClientCertificate,
+		ServerName:           c.ServerName,
+		MinVersion:           tls.VersionTLS12,
+	}
+	if c.CAFile != "" {
+		if tlsConf.RootCAs, err = certs.LoadCertPool(c.CAFile); err != nil {
+			return nil, nil, err
+		}
+	}
+	return credentials.NewTLS(tlsConf), cert, nil
+}
+
+func newCometClient(addr string, creds credentials.TransportCredentials) (*grpc.ClientConn, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
+	transport := grpc.WithInsecure()
+	if creds != nil {
+		transport = grpc.WithTransportCredentials(creds)
+	}
 	conn, err := grpc.DialContext(ctx, addr,
 		[]grpc.DialOption{
-			grpc.WithInsecure(),
+			transport,
 			grpc.WithInitialWindowSize(grpcInitialWindowSize),
 			grpc.WithInitialConnWindowSize(grpcInitialConnWindowSize),
 			grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(grpcMaxCallMsgSize)),
 			grpc.WithDefaultCallOptions(grpc.MaxCallSendMsgSize(grpcMaxSendMsgSize)),
 			grpc.WithBackoffMaxDelay(grpcBackoffMaxDelay),
+			grpc.WithUnaryInterceptor(tracing.UnaryClientInterceptor()),
 			grpc.WithKeepaliveParams(keepalive.ClientParameters{
 				Time:                grpcKeepAliveTime,
 				Timeout:             grpcKeepAliveTimeout,
@@ -85,56 +146,113 @@ func newCometClient(addr string) (connect.CometClient, error) {
 	if err != nil {
 		return nil, err
 	}
-	return connect.NewCometClient(conn), err
+	return conn, err
 }
 
-func (c *ConnectServer) process(pushCh chan *connect.PushMsgReq, roomCh chan *connect.BroadcastRoomReq, broadcastChan chan *connect.BroadcastReq) {
+func (c *ConnectServer) process(pushCh chan keysMsg, roomCh chan roomMsg, broadcastChan chan broadcastMsg) {
+	defer c.wg.Done()
 	for {
 		select {
 		case pushData := <-pushCh:
-			_, err := c.client.PushMsg(context.Background(), pushData)
-			if err != nil {
-				c.log.Error("推送指定key错误", zap.Error(err))
-			}
+			c.pushMsg(pushData.ctx, pushData.req)
 		case roomData := <-roomCh:
-			_, err := c.client.BroadcastRoom(context.Background(), roomData)
-			if err != nil {
-				c.log.Error("推送指定房间错误", zap.Error(err))
-			}
+			c.broadcastRoom(roomData.ctx, roomData.req)
 		case broadcastData := <-broadcastChan:
-			_, err := c.client.Broadcast(context.Background(), broadcastData)
-			if err != nil {
-				c.log.Error("广播错误", zap.Error(err))
+			c.broadcast(broadcastData.ctx, broadcastData.req)
+		case <-c.ctx.Done():
+			//comet下线, 把已经分发到channel中的消息推送完再退出
+			for {
+				select {
+				case pushData := <-pushCh:
+					c.pushMsg(pushData.ctx, pushData.req)
+				case roomData := <-roomCh:
+					c.broadcastRoom(roomData.ctx, roomData.req)
+				case broadcastData := <-broadcastChan:
+					c.broadcast(broadcastData.ctx, broadcastData.req)
+				default:
+					return
+				}
 			}
 		}
 	}
 }
 
-func (c *ConnectServer) PushKey(msg *connect.PushMsgReq) error {
+func (c *ConnectServer) pushMsg(ctx context.Context, req *connect.PushMsgReq) {
+	start := time.Now()
+	_, err := c.client.PushMsg(ctx, req)
+	observePush("push", start, err)
+	pushSpanFrom(ctx).done(err)
+	if err != nil {
+		c.log.Error("推送指定key错误", zap.String("server", c.serverId), zap.Error(err))
+	}
+}
+
+func (c *ConnectServer) broadcastRoom(ctx context.Context, req *connect.BroadcastRoomReq) {
+	start := time.Now()
+	_, err := c.client.BroadcastRoom(ctx, req)
+	observePush("room", start, err)
+	pushSpanFrom(ctx).done(err)
+	if err != nil {
+		c.log.Error("推送指定房间错误", zap.String("server", c.serverId), zap.Error(err))
+	}
+}
+
+func (c *ConnectServer) broadcast(ctx context.Context, req *connect.BroadcastReq) {
+	start := time.Now()
+	_, err := c.client.Broadcast(ctx, req)
+	observePush("broadcast", start, err)
+	pushSpanFrom(ctx).done(err)
+	if err != nil {
+		c.log.Error("广播错误", zap.String("server", c.serverId), zap.Error(err))
+	}
+}
+
+// Close stop accepting messages, push the pending ones and close the grpc connection.
+func (c *ConnectServer) Close() error {
+	c.cancel()
+	c.wg.Wait()
+	return c.conn.Close()
+}
+
+func (c *ConnectServer) PushKey(ctx context.Context, msg *connect.PushMsgReq) error {
 	index := atomic.AddUint64(&c.pushChanNum, 1) % c.routineSize
+	pushSpanFrom(ctx).add()
 	select {
-	case c.pushCh[index] <- msg:
+	case c.pushCh[index] <- keysMsg{ctx: ctx, req: msg}:
 	default:
+		pushSpanFrom(ctx).done(nil)
 		return errors.New(fmt.Sprintf("serverId:%s index:%d pushKeyChan not enough", c.serverId, index))
 	}
 	return nil
 }
 
-func (c *ConnectServer) PushRoom(msg *connect.BroadcastRoomReq) error {
+func (c *ConnectServer) PushRoom(ctx context.Context, msg *connect.BroadcastRoomReq) error {
 	index := atomic.AddUint64(&c.roomChanNum, 1) % c.routineSize
+	pushSpanFrom(ctx).add()
 	select {
-	case c.roomCh[index] <- msg:
+	case c.roomCh[index] <- roomMsg{ctx: ctx, req: msg}:
 	default:
+		pushSpanFrom(ctx).done(nil)
 		return errors.New(fmt.Sprintf("serverId:%s index:%d broadcastRoomChan not enough", c.serverId, index))
 	}
 	return nil
 }
 
-func (c *ConnectServer) Broadcast(msg *connect.BroadcastReq) error {
+func (c *ConnectServer) Broadcast(ctx context.Context, msg *connect.BroadcastReq) error {
+	pushSpanFrom(ctx).add()
 	select {
-	case c.broadcastChan <- msg:
+	case c.broadcastChan <- broadcastMsg{ctx: ctx, req: msg}:
 	default:
+		pushSpanFrom(ctx).done(nil)
 		return errors.New(fmt.Sprintf("serverId:%s broadcastChan not enough", c.serverId))
 	}
 	return nil
 }
+
+// Kick kick the keys off the comet synchronously.
+func (c *ConnectServer) Kick(ctx context.Context, req *connect.KickReq) error {
+	start := time.Now()
+	_, err := c.client.Kick(ctx, req)
+	observePush("kick", start, err)
+	return err
+}
@@ -5,8 +5,14 @@ import (
 	"fmt"
 	"github.com/Shopify/sarama"
 	pb "go-im/api/logic"
+	"go-im/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"google.golang.org/protobuf/proto"
+	"strconv"
+	"sync/atomic"
 	"time"
 )
 
@@ -39,7 +45,12 @@ func (k *Kafka) Cleanup(sarama.ConsumerGroupSession) error {
 // ConsumeClaim must start a consumer loop of ConsumerGroupClaim's Messages().
 func (k *Kafka) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
 	// 具体消费消息
+	lag := consumeLag.WithLabelValues(claim.Topic(), strconv.Itoa(int(claim.Partition())))
 	for message := range claim.Messages() {
+		lag.Set(float64(claim.HighWaterMarkOffset() - message.Offset - 1))
+		if !message.Timestamp.IsZero() {
+			consumeDelay.Observe(time.Since(message.Timestamp).Seconds())
+		}
 		//log.Infof("[topic:%s] [partiton:%d] [offset:%d] [value:%s] [time:%v]",
 		//	message.Topic, message.Partition, message.Offset, string(message.Value), message.Timestamp)
 		pushMsg := new(pb.PushMsg)
@@ -47,9 +58,21 @@ func (k *Kafka) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.C
 			k.s.log.Error(fmt.Sprintf("proto.Unmarshal(%v)", message), zap.Error(err))
 			continue
 		}
-		if err := k.s.push(context.Background(), pushMsg); err != nil {
-			k.s.log.Error("", zap.Error(err))
+		//logic发布时在消息头中携带trace上下文, 推送comet的span作为其子span
+		ctx, span := tracing.Tracer().Start(tracing.ExtractKafka(context.Background(), message.Headers), "kafka consume",
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(
+				attribute.String("messaging.system", "kafka"),
+				attribute.String("messaging.source", message.Topic),
+				attribute.String("goim.msg_id", pushMsg.MsgId),
+			))
+		//推送comet是异步的, 所有推送完成后span才结束
+		ctx, ps := withPushSpan(ctx, span)
+		err := k.s.push(ctx, pushMsg)
+		if err != nil {
+			k.s.log.Error("", zap.String("msg_id", pushMsg.MsgId), zap.Error(err))
 		}
+		ps.done(err)
 		//手动更新位移同时开启幂等
 		session.MarkMessage(message, "")
 	}
@@ -90,3 +113,43 @@ func newKafkaConfig() *sarama.Config {
 	//}
 	return config
 }
+
+// pushSpan end the consume span of a kafka message after all the async pushes
+// to the comets are done, the consumer holds a reference until dispatched.
+type pushSpan struct {
+	span trace.Span
+	refs int32
+}
+
+type pushSpanKey struct{}
+
+func withPushSpan(ctx context.Context, span trace.Span) (context.Context, *pushSpan) {
+	ps := &pushSpan{span: span, refs: 1}
+	return context.WithValue(ctx, pushSpanKey{}, ps), ps
+}
+
+// pushSpanFrom get the push span of ctx, nil if not consumed from kafka.
+func pushSpanFrom(ctx context.Context) *pushSpan {
+	ps, _ := ctx.Value(pushSpanKey{}).(*pushSpan)
+	return ps
+}
+
+// add add a reference for an async push.
+func (ps *pushSpan) add() {
+	if ps != nil {
+		atomic.AddInt32(&ps.refs, 1)
+	}
+}
+
+// done release a reference, the error is recorded, the span ends with the last one.
+func (ps *pushSpan) done(err error) {
+	if ps == nil {
+		return
+	}
+	if err != nil {
+		ps.span.SetStatus(codes.Error, err.Error())
+	}
+	if atomic.AddInt32(&ps.refs, -1) == 0 {
+		ps.span.End()
+	}
+}
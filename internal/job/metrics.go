@@ -0,0 +1,46 @@
+package job
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// consumeLag 分区最新位移与正在消费的位移之差
+	consumeLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "goim",
+		Subsystem: "job",
+		Name:      "consume_lag_messages",
+		Help:      "Messages not consumed yet in the partition.",
+	}, []string{"topic", "partition"})
+	// consumeDelay the time from the message published to consumed.
+	consumeDelay = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "goim",
+		Subsystem: "job",
+		Name:      "consume_delay_seconds",
+		Help:      "Delay from the message published to consumed.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 16),
+	})
+	// cometPush the latency of pushing to comet by grpc.
+	cometPush = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "goim",
+		Subsystem: "job",
+		Name:      "comet_push_duration_seconds",
+		Help:      "Latency of the comet push rpc by the method and result.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 12),
+	}, []string{"method", "result"})
+)
+
+func init() {
+	prometheus.MustRegister(consumeLag, consumeDelay, cometPush)
+}
+
+// observePush observe the latency of a comet push rpc started at start.
+func observePush(method string, start time.Time, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	cometPush.WithLabelValues(method, result).Observe(time.Since(start).Seconds())
+}
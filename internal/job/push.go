@@ -6,47 +6,60 @@ import (
 	"go-im/api/connect"
 	pb "go-im/api/logic"
 	"go-im/api/protocol"
+	"go-im/pkg/proto"
 	"go.uber.org/zap"
 )
 
 func (s *Server) push(ctx context.Context, pushMsg *pb.PushMsg) (err error) {
+	//预压缩的消息在版本号的高位标记压缩算法, 由comet按客户端协商的算法下发
+	codec := proto.Codec(pushMsg.Codec)
+	if pushMsg.Codec < 0 || !codec.Valid() {
+		return fmt.Errorf("unknown codec: %d msg_id: %s", pushMsg.Codec, pushMsg.MsgId)
+	}
+	ver := proto.WithCodec(1, codec)
 	switch pushMsg.Type {
 	case pb.PushMsg_PUSH:
-		err = s.pushKeys(pushMsg.Operation, pushMsg.Server, pushMsg.Keys, pushMsg.Msg)
+		err = s.pushKeys(ctx, ver, pushMsg.Operation, pushMsg.Server, pushMsg.Keys, pushMsg.Msg)
 	case pb.PushMsg_ROOM:
-		err = s.pushRoom(pushMsg.Operation, pushMsg.Server, pushMsg.Room, pushMsg.Msg)
+		err = s.pushRoom(ctx, ver, pushMsg.Operation, pushMsg.Server, pushMsg.Room, pushMsg.Msg)
 	case pb.PushMsg_BROADCAST:
-		err = s.broadcast(pushMsg.Operation, pushMsg.Msg, pushMsg.Speed)
+		err = s.broadcast(ctx, ver, pushMsg.Operation, pushMsg.Msg, pushMsg.Speed)
+	case pb.PushMsg_KICK:
+		err = s.kick(ctx, pushMsg.Server, pushMsg.Keys, pushMsg.Reason)
 	default:
 		err = fmt.Errorf("no match push type: %s", pushMsg.Type)
 	}
 	return
 }
 
-func (s *Server) broadcast(operation int32, body []byte, speed int32) error {
+func (s *Server) broadcast(ctx context.Context, ver, operation int32, body []byte, speed int32) error {
 	p := &protocol.Proto{
-		Ver:  1,
+		Ver:  ver,
 		Op:   operation,
 		Body: body,
 	}
-	speed /= int32(len(s.connect))
-	var args = connect.BroadcastReq{
+	connects := s.Connects()
+	if len(connects) == 0 {
+		return nil
+	}
+	speed /= int32(len(connects))
+	var args = &connect.BroadcastReq{
 		ProtoOp: operation,
 		Proto:   p,
 		Speed:   speed,
 	}
 	var err error
-	for serverID, c := range s.connect {
-		if err = c.Broadcast(&args); err != nil {
+	for serverID, c := range connects {
+		if err = c.Broadcast(ctx, args); err != nil {
 			s.log.Error(fmt.Sprintf("c.Broadcast(%v) serverID:%s  ", args, serverID), zap.Error(err))
 		}
 	}
 	return nil
 }
 
-func (s *Server) pushRoom(operation int32, serverId string, roomId string, body []byte) error {
+func (s *Server) pushRoom(ctx context.Context, ver, operation int32, serverId string, roomId string, body []byte) error {
 	p := &protocol.Proto{
-		Ver:  1,
+		Ver:  ver,
 		Op:   operation,
 		Body: body,
 	}
@@ -56,8 +69,8 @@ func (s *Server) pushRoom(operation int32, serverId string, roomId string, body
 		Proto:  p,
 	}
 	var err error
-	if c, ok := s.connect[serverId]; ok {
-		if err = c.PushRoom(msg); err != nil {
+	if c, ok := s.Connect(serverId); ok {
+		if err = c.PushRoom(ctx, msg); err != nil {
 			s.log.Error("", zap.Error(err))
 		}
 	}
@@ -65,9 +78,9 @@ func (s *Server) pushRoom(operation int32, serverId string, roomId string, body
 }
 
 //个推
-func (s *Server) pushKeys(operation int32, serverId string, keys []string, body []byte) error {
+func (s *Server) pushKeys(ctx context.Context, ver, operation int32, serverId string, keys []string, body []byte) error {
 	p := &protocol.Proto{
-		Ver:  1,
+		Ver:  ver,
 		Op:   operation,
 		Body: body,
 	}
@@ -78,10 +91,22 @@ func (s *Server) pushKeys(operation int32, serverId string, keys []string, body
 		Proto:   p,
 	}
 	var err error
-	if c, ok := s.connect[serverId]; ok {
-		if err = c.PushKey(msg); err != nil {
+	if c, ok := s.Connect(serverId); ok {
+		if err = c.PushKey(ctx, msg); err != nil {
 			s.log.Error("", zap.Error(err))
 		}
 	}
 	return nil
 }
+
+//踢下线, 直接调用不经过推送队列, 避免排在大量消息之后
+func (s *Server) kick(ctx context.Context, serverId string, keys []string, reason string) error {
+	c, ok := s.Connect(serverId)
+	if !ok {
+		return nil
+	}
+	if err := c.Kick(ctx, &connect.KickReq{Keys: keys, Reason: reason}); err != nil {
+		s.log.Error("", zap.String("server", serverId), zap.Error(err))
+	}
+	return nil
+}
@@ -3,9 +3,14 @@ package job
 import (
 	"context"
 	"github.com/Shopify/sarama"
+	"go-im/api/connect"
 	"go-im/internal/job/conf"
+	"go-im/pkg/certs"
+	"go-im/pkg/etcd"
 	"go-im/pkg/log"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/credentials"
+	"strings"
 	"sync"
 )
 
@@ -15,23 +20,112 @@ type Server struct {
 	c       *conf.Config
 	k       *Kafka
 	connect map[string]*ConnectServer
+	creds   credentials.TransportCredentials //comet grpc的mTLS, 为空不加密
+	cert    *certs.Reloader
 }
 
 func NewServer(c *conf.Config) *Server {
+	var err error
 	s := new(Server)
 	s.c = c
 	s.log = log.NewLog("im", c.Mode.Debug)
-	//todo connect
-	connectS, err := newConnectServer(c)
-	if err != nil {
+	s.connect = make(map[string]*ConnectServer)
+	if s.creds, s.cert, err = newCometCreds(c.Comet); err != nil {
+		panic(err)
+	}
+	if err = s.watchConnect(); err != nil {
 		panic(err)
 	}
-	s.connect[connectS.serverId] = connectS
 
 	s.k = NewKafka(s)
 	return s
 }
 
+// watchConnect 通过etcd发现comet, 维护comet的grpc客户端
+func (s *Server) watchConnect() error {
+	dis, err := etcd.NewDiscovery([]string{s.c.Discovery.Host})
+	if err != nil {
+		return err
+	}
+	dis.OnChange(func(key, val string, deleted bool) {
+		serverId := strings.TrimPrefix(key, connect.DiscoveryPrefix)
+		if deleted {
+			s.delConnect(serverId)
+			return
+		}
+		ins, err := connect.ParseInstance(val)
+		if err != nil {
+			s.log.Error("parse connect instance err", zap.String("server", serverId), zap.String("val", val), zap.Error(err))
+			return
+		}
+		s.addConnect(serverId, ins.RPCAddr)
+	})
+	return dis.WatchSrv(connect.DiscoveryPrefix)
+}
+
+func (s *Server) addConnect(serverId, addr string) {
+	s.lock.RLock()
+	old, ok := s.connect[serverId]
+	s.lock.RUnlock()
+	if ok && old.addr == addr {
+		return
+	}
+	c, err := newConnectServer(s.c.Comet, s.creds, serverId, addr, s.log)
+	if err != nil {
+		s.log.Error("new connect server err", zap.String("server", serverId), zap.String("addr", addr), zap.Error(err))
+		return
+	}
+	s.lock.Lock()
+	old = s.connect[serverId]
+	s.connect[serverId] = c
+	s.lock.Unlock()
+	if old != nil {
+		go old.Close()
+	}
+	s.log.Info("connect server online", zap.String("server", serverId), zap.String("addr", addr))
+}
+
+func (s *Server) delConnect(serverId string) {
+	s.lock.Lock()
+	c, ok := s.connect[serverId]
+	delete(s.connect, serverId)
+	s.lock.Unlock()
+	if ok {
+		//不阻塞etcd的watch
+		go c.Close()
+		s.log.Info("connect server offline", zap.String("server", serverId))
+	}
+}
+
+// Connect get the comet by server id.
+func (s *Server) Connect(serverId string) (c *ConnectServer, ok bool) {
+	s.lock.RLock()
+	c, ok = s.connect[serverId]
+	s.lock.RUnlock()
+	return
+}
+
+// Connects get all comets.
+func (s *Server) Connects() map[string]*ConnectServer {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	cs := make(map[string]*ConnectServer, len(s.connect))
+	for serverId, c := range s.connect {
+		cs[serverId] = c
+	}
+	return cs
+}
+
+// ReloadCerts reload the client certificate of comet grpc, used by the new connections.
+func (s *Server) ReloadCerts() {
+	if s.cert == nil {
+		return
+	}
+	if err := s.cert.Reload(); err != nil {
+		s.log.Error("reload certificate err", zap.Error(err))
+	}
+}
+
 func (s *Server) Consume() {
 	config := newKafkaConfig()
 
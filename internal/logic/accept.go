@@ -0,0 +1,41 @@
+package logic
+
+import (
+	"go-im/internal/logic/conf"
+	"go-im/pkg/opset"
+)
+
+// newAllowOps parse the policy of the ops clients may subscribe, nil if not limited.
+func newAllowOps(c *conf.Accept) opset.Set {
+	if c == nil || len(c.Allow) == 0 {
+		return nil
+	}
+	allow, err := opset.Parse(c.Allow...)
+	if err != nil {
+		panic(err)
+	}
+	return allow
+}
+
+// filterAccepts drop the ops not allowed by the policy.
+func (l *Logic) filterAccepts(accepts []int32) []int32 {
+	if l.allowOps == nil {
+		return accepts
+	}
+	res := make([]int32, 0, len(accepts))
+	for _, op := range accepts {
+		if l.allowOps.Has(op) {
+			res = append(res, op)
+		}
+	}
+	return res
+}
+
+// Allow get the specs of the ops clients may subscribe, the comets check
+// OpSub by it. It's empty if not limited.
+func (l *Logic) Allow() []string {
+	if l.allowOps == nil {
+		return nil
+	}
+	return []string{l.allowOps.String()}
+}
@@ -0,0 +1,22 @@
+package logic
+
+import (
+	"reflect"
+	"testing"
+
+	"go-im/internal/logic/conf"
+)
+
+func TestFilterAccepts(t *testing.T) {
+	l := &Logic{}
+	if got := l.filterAccepts([]int32{1, 1000}); !reflect.DeepEqual(got, []int32{1, 1000}) || l.Allow() != nil {
+		t.Fatalf("not limited got %v", got)
+	}
+	l.allowOps = newAllowOps(&conf.Accept{Allow: []string{"1000-1999", "3000"}})
+	if got := l.filterAccepts([]int32{1, 1000, 2000, 3000}); !reflect.DeepEqual(got, []int32{1000, 3000}) {
+		t.Fatalf("filtered got %v", got)
+	}
+	if got := l.Allow(); !reflect.DeepEqual(got, []string{"1000-1999,3000"}) {
+		t.Fatalf("allow %v", got)
+	}
+}
@@ -0,0 +1,95 @@
+package logic
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v4"
+	"go-im/internal/logic/conf"
+	"go-im/internal/logic/dao"
+	model "go-im/internal/logic/dto"
+)
+
+var (
+	// ErrAuthFailed token verify failed.
+	ErrAuthFailed = errors.New("auth failed")
+	// ErrRoomNotAllowed member is not allowed to join the room.
+	ErrRoomNotAllowed = errors.New("room not allowed")
+	// errEmptySecret the jwt secret is not configured.
+	errEmptySecret = errors.New("auth: jwt secret is empty")
+)
+
+// Authenticator verify the token sent by client in OpAuth.
+type Authenticator interface {
+	Auth(c context.Context, token string) (*model.Identity, error)
+}
+
+func newAuthenticator(c *conf.Auth, d *dao.Dao) (Authenticator, error) {
+	switch c.Driver {
+	case "redis":
+		return &redisAuth{dao: d}, nil
+	default:
+		if c.Secret == "" {
+			return nil, errEmptySecret
+		}
+		return &jwtAuth{secret: []byte(c.Secret)}, nil
+	}
+}
+
+// tokenDigest the short hash of the token for logging, the token itself must not be logged.
+func tokenDigest(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:8])
+}
+
+// jwtAuth verify HMAC signed JWT.
+type jwtAuth struct {
+	secret []byte
+}
+
+type jwtClaims struct {
+	Mid      int64    `json:"mid"`
+	Platform string   `json:"platform"`
+	Rooms    []string `json:"rooms"`
+	jwt.RegisteredClaims
+}
+
+func (a *jwtAuth) Auth(c context.Context, token string) (*model.Identity, error) {
+	claims := new(jwtClaims)
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return a.secret, nil
+	})
+	if err != nil || claims.Mid == 0 {
+		return nil, ErrAuthFailed
+	}
+	return &model.Identity{
+		Mid:      claims.Mid,
+		Platform: claims.Platform,
+		Rooms:    claims.Rooms,
+	}, nil
+}
+
+// redisAuth verify opaque token stored in redis by the login service.
+type redisAuth struct {
+	dao *dao.Dao
+}
+
+func (a *redisAuth) Auth(c context.Context, token string) (*model.Identity, error) {
+	if token == "" {
+		return nil, ErrAuthFailed
+	}
+	identity, err := a.dao.Identity(c, token)
+	if err != nil {
+		return nil, err
+	}
+	if identity == nil || identity.Mid == 0 {
+		return nil, ErrAuthFailed
+	}
+	return identity, nil
+}
@@ -0,0 +1,54 @@
+package logic
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+	"go-im/internal/logic/conf"
+)
+
+func TestJwtAuth(t *testing.T) {
+	a := &jwtAuth{secret: []byte("goim")}
+	sign := func(method jwt.SigningMethod, key interface{}, claims *jwtClaims) string {
+		token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	token := sign(jwt.SigningMethodHS256, []byte("goim"), &jwtClaims{Mid: 123, Platform: "web", Rooms: []string{"live://1000"}})
+	identity, err := a.Auth(context.Background(), token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Mid != 123 || identity.Platform != "web" {
+		t.Fatalf("wrong identity %+v", identity)
+	}
+	if !identity.AllowRoom("live://1000") || identity.AllowRoom("live://1001") {
+		t.Fatalf("wrong allowed rooms %v", identity.Rooms)
+	}
+	// wrong secret
+	token = sign(jwt.SigningMethodHS256, []byte("other"), &jwtClaims{Mid: 123})
+	if _, err = a.Auth(context.Background(), token); err != ErrAuthFailed {
+		t.Fatalf("want ErrAuthFailed, got %v", err)
+	}
+	// unsigned token
+	token = sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, &jwtClaims{Mid: 123})
+	if _, err = a.Auth(context.Background(), token); err != ErrAuthFailed {
+		t.Fatalf("want ErrAuthFailed, got %v", err)
+	}
+}
+
+func TestNewAuthenticator(t *testing.T) {
+	if _, err := newAuthenticator(&conf.Auth{Driver: "jwt"}, nil); err != errEmptySecret {
+		t.Fatalf("empty secret err %v", err)
+	}
+	if a, err := newAuthenticator(&conf.Auth{Driver: "jwt", Secret: "goim"}, nil); err != nil || a == nil {
+		t.Fatal(err)
+	}
+	if d := tokenDigest("token"); len(d) != 16 || strings.Contains(d, "token") {
+		t.Fatalf("digest %s", d)
+	}
+}
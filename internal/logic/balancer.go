@@ -0,0 +1,111 @@
+package logic
+
+import (
+	"sort"
+	"sync"
+
+	pb "go-im/api/connect"
+)
+
+const (
+	_maxNodes = 5
+)
+
+type weightedNode struct {
+	region   string
+	hostname string
+	addrs    []string
+	weight   int64 // 配置的权重
+	conns    int64 // 注册的连接数加上之后分配出去的数量
+	updated  int64
+	score    float64
+}
+
+// calculate the node score by weight per connection, gain is the weight
+// multiple for the nodes in the same region with the client.
+func (n *weightedNode) calculate(gain float64) {
+	n.score = float64(n.weight) * gain / float64(n.conns+1)
+}
+
+// LoadBalancer dispatches clients to the comets by weight and load.
+type LoadBalancer struct {
+	lock  sync.Mutex
+	nodes map[string]*weightedNode
+}
+
+// NewLoadBalancer new a load balancer.
+func NewLoadBalancer() *LoadBalancer {
+	return &LoadBalancer{
+		nodes: make(map[string]*weightedNode),
+	}
+}
+
+// Size get the count of online nodes.
+func (lb *LoadBalancer) Size() int {
+	lb.lock.Lock()
+	defer lb.lock.Unlock()
+	return len(lb.nodes)
+}
+
+// Update reset the nodes by the registered comets, the offline ones are skipped.
+func (lb *LoadBalancer) Update(ins map[string]*pb.Instance) {
+	nodes := make(map[string]*weightedNode, len(ins))
+	lb.lock.Lock()
+	defer lb.lock.Unlock()
+	for _, in := range ins {
+		if in.Offline || in.Weight <= 0 {
+			continue
+		}
+		//负载没有刷新, 保留分配出去的连接数
+		if old, ok := lb.nodes[in.Hostname]; ok && old.updated == in.Updated {
+			nodes[in.Hostname] = old
+			continue
+		}
+		nodes[in.Hostname] = &weightedNode{
+			region:   in.Region,
+			hostname: in.Hostname,
+			addrs:    in.Addrs,
+			weight:   in.Weight,
+			conns:    int64(in.ConnCount),
+			updated:  in.Updated,
+		}
+	}
+	lb.nodes = nodes
+}
+
+// NodeAddrs get the domains and addrs of the best nodes for a client in the region.
+func (lb *LoadBalancer) NodeAddrs(region, domain string, regionWeight float64) (domains, addrs []string) {
+	for i, n := range lb.weightedNodes(region, regionWeight) {
+		if i == _maxNodes {
+			break
+		}
+		domains = append(domains, n.hostname+domain)
+		addrs = append(addrs, n.addrs...)
+	}
+	return
+}
+
+func (lb *LoadBalancer) weightedNodes(region string, regionWeight float64) []*weightedNode {
+	lb.lock.Lock()
+	defer lb.lock.Unlock()
+	nodes := make([]*weightedNode, 0, len(lb.nodes))
+	for _, n := range lb.nodes {
+		gain := 1.0
+		if region != "" && n.region == region {
+			gain = regionWeight
+		}
+		n.calculate(gain)
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].score == nodes[j].score {
+			return nodes[i].hostname < nodes[j].hostname
+		}
+		return nodes[i].score > nodes[j].score
+	})
+	//客户端大概率连接第一个节点, 计入负载直到下次刷新
+	if len(nodes) > 0 {
+		nodes[0].conns++
+	}
+	return nodes
+}
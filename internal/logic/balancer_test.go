@@ -0,0 +1,38 @@
+package logic
+
+import (
+	"testing"
+
+	pb "go-im/api/connect"
+)
+
+func TestLoadBalancer(t *testing.T) {
+	lb := NewLoadBalancer()
+	lb.Update(map[string]*pb.Instance{
+		"a": {Hostname: "a", Region: "sh", Addrs: []string{"10.0.0.1"}, Weight: 10, ConnCount: 100, Updated: 1},
+		"b": {Hostname: "b", Region: "bj", Addrs: []string{"10.0.0.2"}, Weight: 10, ConnCount: 50, Updated: 1},
+		"c": {Hostname: "c", Region: "bj", Addrs: []string{"10.0.0.3"}, Weight: 10, Offline: true, Updated: 1},
+	})
+	if lb.Size() != 2 {
+		t.Fatalf("offline node should be skipped, size %d", lb.Size())
+	}
+	// less loaded first
+	domains, addrs := lb.NodeAddrs("", ".goim.io", 1.6)
+	if len(domains) != 2 || domains[0] != "b.goim.io" || addrs[0] != "10.0.0.2" {
+		t.Fatalf("wrong order %v %v", domains, addrs)
+	}
+	// region gain beats the load
+	if _, addrs = lb.NodeAddrs("sh", ".goim.io", 3); addrs[0] != "10.0.0.1" {
+		t.Fatalf("region not preferred %v", addrs)
+	}
+	// dispatched clients count as load until the next refresh
+	for i := 0; i < 100; i++ {
+		lb.NodeAddrs("", "", 1)
+	}
+	lb.lock.Lock()
+	a, b := lb.nodes["a"].conns, lb.nodes["b"].conns
+	lb.lock.Unlock()
+	if d := a - b; d > 1 || d < -1 {
+		t.Fatalf("unbalanced conns a:%d b:%d", a, b)
+	}
+}
@@ -2,6 +2,7 @@ package conf
 
 import (
 	"github.com/spf13/viper"
+	"go-im/pkg/tracing"
 	"strings"
 	"time"
 )
@@ -19,7 +20,7 @@ func Parse(path string) {
 	ps := strings.Split(path, "/")
 	//文件路径前缀
 	p := strings.Join(ps[:len(ps)-1], "/")
-	v.SetConfigName("job")
+	v.SetConfigName("logic")
 	v.SetConfigType("yaml")
 	v.AddConfigPath(p)
 	if err := v.ReadInConfig(); err != nil {
@@ -42,12 +43,23 @@ type Config struct {
 	Node       *Node
 	Backoff    *Backoff
 	Regions    map[string][]string
+	Offline    *Offline
+	Auth       *Auth
+	Limit      *Limit
+	Accept     *Accept
+	Tracing    *tracing.Config
+	Metrics    *Metrics
+}
+
+// Metrics is the prometheus metrics endpoint config.
+type Metrics struct {
+	Addr string
 }
 
 type Discovery struct {
-	driver  string
-	host    string
-	timeout int
+	Driver  string
+	Host    string
+	Timeout int
 }
 
 // Env is env config.
@@ -69,6 +81,7 @@ type Node struct {
 	HeartbeatMax  int
 	Heartbeat     time.Duration
 	RegionWeight  float64
+	Provinces     map[string][]string //省份 -> 客户端ip网段, 按Regions优先返回同区域的comet, 为空不区分区域
 }
 
 type Backoff struct {
@@ -92,6 +105,31 @@ type Redis struct {
 	Expire       time.Duration
 }
 
+// Auth is token auth config.
+type Auth struct {
+	Driver string // jwt or redis
+	Secret string // jwt HMAC secret
+}
+
+// Offline is offline mailbox config.
+type Offline struct {
+	Open   bool
+	Driver string        // redis or memory
+	Expire time.Duration // 离线消息保存时间
+	Max    int           // 每个用户最多保存的离线消息条数
+}
+
+// Limit is connection limit config.
+type Limit struct {
+	MaxMidConns int    // 每个用户在集群内的最大连接数, 0不限制
+	MidPolicy   string // reject or kick, 超过限制时拒绝新连接或踢掉最早的
+}
+
+// Accept is the policy of the ops clients may subscribe.
+type Accept struct {
+	Allow []string // 客户端可以订阅的op, 如"1000-1999", 为空不限制
+}
+
 // Kafka .
 type Kafka struct {
 	Topic   string
@@ -122,4 +160,5 @@ type HTTPServer struct {
 	Addr         string
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
+	AdminToken   string //踢人等管理接口的Bearer token, 为空时管理接口不可用
 }
@@ -3,58 +3,135 @@ package logic
 import (
 	"context"
 	"encoding/json"
+	log "github.com/golang/glog"
 	"github.com/google/uuid"
+	"go-im/api/logic"
+	"go-im/api/protocol"
 	model "go-im/internal/logic/dto"
-	"log"
+	"strconv"
 	"time"
 )
 
 // Connect connected a conn.
-func (l *Logic) Connect(c context.Context, server, cookie string, token []byte) (mid int64, key, roomID string, accepts []int32, hb int64, err error) {
-	var params struct {
-		Mid      int64   `json:"mid"`
-		Key      string  `json:"key"`
-		RoomID   string  `json:"room_id"`
-		Platform string  `json:"platform"`
-		Accepts  []int32 `json:"accepts"`
-	}
+// token is the OpAuth body: {"token":"...","key":"...","room_id":"live://1000","accepts":[1000,1001]}
+// rooms is the rooms the member may join after connected, empty means no limit.
+func (l *Logic) Connect(c context.Context, server, cookie string, token []byte) (mid int64, key, roomID string, accepts []int32, rooms []string, hb int64, err error) {
+	var (
+		params struct {
+			Token   string  `json:"token"`
+			Key     string  `json:"key"`
+			RoomID  string  `json:"room_id"`
+			Accepts []int32 `json:"accepts"`
+		}
+		identity *model.Identity
+	)
 	if err = json.Unmarshal(token, &params); err != nil {
-		log.Fatalf("json.Unmarshal(%s) error(%v)", token, err)
+		log.Errorf("json.Unmarshal(auth body) server:%s len:%d error(%v)", server, len(token), err)
+		err = ErrAuthFailed
+		return
+	}
+	if identity, err = l.auth.Auth(c, params.Token); err != nil {
+		log.Errorf("l.auth.Auth(%s) server:%s error(%v)", tokenDigest(params.Token), server, err)
+		return
+	}
+	if !identity.AllowRoom(params.RoomID) {
+		err = ErrRoomNotAllowed
+		return
+	}
+	mid = identity.Mid
+	if err = l.checkBanned(c, mid); err != nil {
 		return
 	}
-	mid = params.Mid
 	roomID = params.RoomID
-	accepts = params.Accepts
+	rooms = identity.Rooms
+	accepts = l.filterAccepts(params.Accepts)
 	hb = int64(l.c.Node.Heartbeat) * int64(l.c.Node.HeartbeatMax)
-	if key = params.Key; key == "" {
-		key = uuid.New().String()
+	key = connKey(mid, params.Key)
+	if err = l.addMapping(c, mid, key, server); err != nil {
+		return
 	}
-	if err = l.dao.AddMapping(c, mid, key, server); err != nil {
-		log.Fatalf("l.dao.AddMapping(%d,%s,%s) error(%v)", mid, key, server, err)
+	if l.offline != nil {
+		//comet把连接放入bucket后通过Heartbeat通知, 再重放离线消息
+		accepts = append(accepts, protocol.OpOfflineMsg)
 	}
-	log.Fatalf("conn connected key:%s server:%s mid:%d token:%s", key, server, mid, token)
+	log.Infof("conn connected key:%s server:%s mid:%d platform:%s", key, server, mid, identity.Platform)
 	return
 }
 
+// connKey namespace the key given by the client with the mid, so a member can
+// neither take over nor kick the conns of the others by using their keys.
+func connKey(mid int64, key string) string {
+	if key == "" {
+		return uuid.New().String()
+	}
+	return strconv.FormatInt(mid, 10) + "_" + key
+}
+
 // Disconnect disconnect a conn.
 func (l *Logic) Disconnect(c context.Context, mid int64, key, server string) (has bool, err error) {
 	if has, err = l.dao.DelMapping(c, mid, key, server); err != nil {
-		log.Fatalf("l.dao.DelMapping(%d,%s) error(%v)", mid, key, server)
+		log.Errorf("l.dao.DelMapping(%d,%s,%s) error(%v)", mid, key, server, err)
+		return
+	}
+	log.Infof("conn disconnected key:%s server:%s mid:%d", key, server, mid)
+	return
+}
+
+// DisconnectBatch disconnect the conns closed together by a draining comet.
+func (l *Logic) DisconnectBatch(c context.Context, server string, conns []*logic.Conn) (err error) {
+	if len(conns) == 0 {
+		return
+	}
+	if err = l.dao.DelMappings(c, server, conns); err != nil {
+		log.Errorf("l.dao.DelMappings(%s,%d) error(%v)", server, len(conns), err)
+		return
+	}
+	log.Infof("conns disconnected server:%s count:%d", server, len(conns))
+	return
+}
+
+// Heartbeat renew the mapping of a conn, recreate it if expired.
+// registered is true if the conn was just put into the bucket of the comet, the
+// offline messages are replayed then so they won't find no channel.
+// revoked is true if the session was revoked or the recreated mapping is over
+// the limit, and the conn must auth again.
+func (l *Logic) Heartbeat(c context.Context, mid int64, key, server string, registered bool) (revoked bool, err error) {
+	if registered && l.offline != nil {
+		go l.drainOffline(context.Background(), mid, key, server)
+	}
+	has, err := l.dao.ExpireMapping(c, mid, key)
+	if err != nil {
+		log.Errorf("l.dao.ExpireMapping(%d,%s,%s) error(%v)", mid, key, server, err)
+		return
+	}
+	if has {
+		return
+	}
+	if revoked, err = l.dao.Revoked(c, key); err != nil || revoked {
+		return
+	}
+	//重建的mapping同样受连接数限制, 超过限制时断开连接
+	if err = l.addMapping(c, mid, key, server); err != nil {
+		if err == ErrTooManyConns {
+			revoked, err = true, nil
+		}
 		return
 	}
-	log.Fatalf("conn disconnected key:%s server:%s mid:%d", key, server, mid)
+	log.Infof("conn heartbeat recreate mapping key:%s server:%s mid:%d", key, server, mid)
 	return
 }
 
 // RenewOnline renew a server online.
-func (l *Logic) RenewOnline(c context.Context, server string, roomCount map[string]int32) (map[string]int32, error) {
+func (l *Logic) RenewOnline(c context.Context, server string, roomCount map[string]int32, ipCount, connCount int32) (map[string]int32, error) {
 	online := &model.Online{
 		Server:    server,
 		RoomCount: roomCount,
+		IPCount:   ipCount,
+		ConnCount: connCount,
 		Updated:   time.Now().Unix(),
 	}
 	if err := l.dao.AddServerOnline(context.Background(), server, online); err != nil {
 		return nil, err
 	}
-	return l.roomCount, nil
+	return l.RoomCount(), nil
 }
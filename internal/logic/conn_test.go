@@ -0,0 +1,12 @@
+package logic
+
+import "testing"
+
+func TestConnKey(t *testing.T) {
+	if a, b := connKey(1, "k"), connKey(2, "k"); a == b || a != "1_k" {
+		t.Fatalf("keys of different mids %s %s", a, b)
+	}
+	if connKey(1, "") == connKey(1, "") {
+		t.Fatal("generated keys are the same")
+	}
+}
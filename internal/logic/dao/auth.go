@@ -0,0 +1,39 @@
+package dao
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gomodule/redigo/redis"
+	model "go-im/internal/logic/dto"
+)
+
+const (
+	_prefixToken = "token_%s" // token -> identity, written by the login service
+)
+
+func keyToken(token string) string {
+	return fmt.Sprintf(_prefixToken, token)
+}
+
+// Identity get the member identity of an opaque token, returns nil if not exist.
+func (d *Dao) Identity(c context.Context, token string) (identity *model.Identity, err error) {
+	conn := d.redis.Get()
+	defer conn.Close()
+	b, err := redis.Bytes(conn.Do("GET", keyToken(token)))
+	if err != nil {
+		if err == redis.ErrNil {
+			err = nil
+		} else {
+			//不记录token本身
+			d.log.Error(fmt.Sprintf("conn.Do(GET token) error(%v)", err))
+		}
+		return
+	}
+	identity = new(model.Identity)
+	if err = json.Unmarshal(b, identity); err != nil {
+		d.log.Error(fmt.Sprintf("identity json.Unmarshal(%s) error(%v)", b, err))
+	}
+	return
+}
@@ -1,10 +1,16 @@
 package dao
 
 import (
+	"context"
 	kafka "github.com/Shopify/sarama"
 	"github.com/gomodule/redigo/redis"
+	"github.com/google/uuid"
 	"go-im/internal/logic/conf"
 	"go-im/pkg/log"
+	"go-im/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"time"
 )
 
@@ -40,6 +46,33 @@ func newKafkaPub(c *conf.Kafka) kafka.SyncProducer {
 	return pub
 }
 
+// sendMessage publish the message to kafka and observe the latency, the
+// trace context of c is carried in the message headers.
+func (d *Dao) sendMessage(c context.Context, typ, msgID string, m *kafka.ProducerMessage) (partition int32, offset int64, err error) {
+	c, span := tracing.Tracer().Start(c, "kafka publish "+typ, trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.destination", m.Topic),
+			attribute.String("goim.msg_id", msgID),
+		))
+	defer span.End()
+	tracing.InjectKafka(c, m)
+	start := time.Now()
+	partition, offset, err = d.kafkaPub.SendMessage(m)
+	result := "ok"
+	if err != nil {
+		result = "error"
+		span.SetStatus(codes.Error, err.Error())
+	}
+	kafkaPublish.WithLabelValues(typ, result).Observe(time.Since(start).Seconds())
+	return
+}
+
+// newMsgID get a unique id of the push message.
+func newMsgID() string {
+	return uuid.New().String()
+}
+
 func newRedis(c *conf.Redis) *redis.Pool {
 	return &redis.Pool{
 		MaxIdle:     c.Idle,
@@ -0,0 +1,51 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	_prefixGroup   = "group_%s"   // group -> mids(set), written by the business service
+	_prefixContact = "contact_%d" // mid -> mids it may send to(set), written by the business service
+)
+
+func keyGroup(group string) string {
+	return fmt.Sprintf(_prefixGroup, group)
+}
+
+func keyContact(mid int64) string {
+	return fmt.Sprintf(_prefixContact, mid)
+}
+
+// IsGroupMember reports whether the member is in the group.
+func (d *Dao) IsGroupMember(c context.Context, group string, mid int64) (ok bool, err error) {
+	conn := d.redis.Get()
+	defer conn.Close()
+	if ok, err = redis.Bool(conn.Do("SISMEMBER", keyGroup(group), mid)); err != nil {
+		d.log.Error(fmt.Sprintf("conn.Do(SISMEMBER %s,%d) error(%v)", keyGroup(group), mid, err))
+	}
+	return
+}
+
+// GroupMembers get all members of the group.
+func (d *Dao) GroupMembers(c context.Context, group string) (mids []int64, err error) {
+	conn := d.redis.Get()
+	defer conn.Close()
+	if mids, err = redis.Int64s(conn.Do("SMEMBERS", keyGroup(group))); err != nil {
+		d.log.Error(fmt.Sprintf("conn.Do(SMEMBERS %s) error(%v)", keyGroup(group), err))
+	}
+	return
+}
+
+// IsContact reports whether the member may send to the peer.
+func (d *Dao) IsContact(c context.Context, mid, peer int64) (ok bool, err error) {
+	conn := d.redis.Get()
+	defer conn.Close()
+	if ok, err = redis.Bool(conn.Do("SISMEMBER", keyContact(mid), peer)); err != nil {
+		d.log.Error(fmt.Sprintf("conn.Do(SISMEMBER %s,%d) error(%v)", keyContact(mid), peer, err))
+	}
+	return
+}
@@ -0,0 +1,68 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/gomodule/redigo/redis"
+	pb "go-im/api/logic"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	_prefixMidBanned = "ban_%d" // mid -> banned until the key expires
+)
+
+func keyMidBanned(mid int64) string {
+	return fmt.Sprintf(_prefixMidBanned, mid)
+}
+
+// BanMid ban the member from connecting for the duration.
+func (d *Dao) BanMid(c context.Context, mid int64, ban time.Duration) (err error) {
+	conn := d.redis.Get()
+	defer conn.Close()
+	sec := int64(ban / time.Second)
+	if sec <= 0 {
+		sec = 1
+	}
+	if _, err = conn.Do("SETEX", keyMidBanned(mid), sec, 1); err != nil {
+		d.log.Error(fmt.Sprintf("conn.Do(SETEX %s) error(%v)", keyMidBanned(mid), err))
+	}
+	return
+}
+
+// Banned reports whether the member is banned.
+func (d *Dao) Banned(c context.Context, mid int64) (banned bool, err error) {
+	conn := d.redis.Get()
+	defer conn.Close()
+	if banned, err = redis.Bool(conn.Do("EXISTS", keyMidBanned(mid))); err != nil {
+		d.log.Error(fmt.Sprintf("conn.Do(EXISTS %s) error(%v)", keyMidBanned(mid), err))
+	}
+	return
+}
+
+// KickMsg tell the comet to kick the keys off with the reason.
+func (d *Dao) KickMsg(c context.Context, server string, keys []string, reason string) (err error) {
+	kickMsg := &pb.PushMsg{
+		Type:   pb.PushMsg_KICK,
+		Server: server,
+		Keys:   keys,
+		Reason: reason,
+		MsgId:  newMsgID(),
+	}
+	b, err := proto.Marshal(kickMsg)
+	if err != nil {
+		return
+	}
+	m := &sarama.ProducerMessage{
+		Key:   sarama.StringEncoder(keys[0]),
+		Topic: d.c.Kafka.Topic,
+		Value: sarama.ByteEncoder(b),
+	}
+	if _, _, err = d.sendMessage(c, "kick", kickMsg.MsgId, m); err != nil {
+		d.log.Error(fmt.Sprintf("KickMsg.send(kickMsg:%v) error(%v)", kickMsg, err))
+	}
+	return
+}
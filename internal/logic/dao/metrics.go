@@ -0,0 +1,16 @@
+package dao
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// kafkaPublish the latency of publishing the push messages to kafka.
+var kafkaPublish = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "goim",
+	Subsystem: "logic",
+	Name:      "kafka_publish_duration_seconds",
+	Help:      "Latency of publishing push messages to kafka by the push type and result.",
+	Buckets:   prometheus.ExponentialBuckets(0.001, 2, 12),
+}, []string{"type", "result"})
+
+func init() {
+	prometheus.MustRegister(kafkaPublish)
+}
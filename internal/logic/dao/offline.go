@@ -0,0 +1,208 @@
+package dao
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"go-im/internal/logic/conf"
+	model "go-im/internal/logic/dto"
+)
+
+const (
+	_prefixMidOffline    = "offline_%d"     // mid -> offline messages(zset, score is msg id)
+	_prefixMidOfflineSeq = "offline_seq_%d" // mid -> last offline msg id
+)
+
+func keyMidOffline(mid int64) string {
+	return fmt.Sprintf(_prefixMidOffline, mid)
+}
+
+func keyMidOfflineSeq(mid int64) string {
+	return fmt.Sprintf(_prefixMidOfflineSeq, mid)
+}
+
+// Offline is a per-member offline mailbox.
+// Messages stay in the mailbox until expired, evicted by the cap or acked by the member.
+type Offline interface {
+	// AddOffline put a message into the member's mailbox.
+	AddOffline(c context.Context, mid int64, op int32, msg []byte) (err error)
+	// Offlines get all unexpired messages of the member, ordered by id.
+	Offlines(c context.Context, mid int64) (msgs []*model.OfflineMsg, err error)
+	// AckOffline remove all messages whose id <= the acked id.
+	AckOffline(c context.Context, mid int64, id int64) (err error)
+}
+
+var (
+	_ Offline = &Dao{}
+	_ Offline = &MemoryOffline{}
+)
+
+// AddOffline add a message to the member's offline mailbox in redis.
+func (d *Dao) AddOffline(c context.Context, mid int64, op int32, msg []byte) (err error) {
+	conn := d.redis.Get()
+	defer conn.Close()
+	id, err := redis.Int64(conn.Do("INCR", keyMidOfflineSeq(mid)))
+	if err != nil {
+		d.log.Error(fmt.Sprintf("conn.Do(INCR %s) error(%v)", keyMidOfflineSeq(mid), err))
+		return
+	}
+	b, _ := json.Marshal(&model.OfflineMsg{
+		ID:        id,
+		Operation: op,
+		Msg:       msg,
+		Created:   time.Now().Unix(),
+	})
+	key := keyMidOffline(mid)
+	expire := int32(d.c.Offline.Expire / time.Second)
+	if err = conn.Send("ZADD", key, id, b); err != nil {
+		d.log.Error(fmt.Sprintf("conn.Send(ZADD %s,%d) error(%v)", key, id, err))
+		return
+	}
+	n := 1
+	//只保留最新的max条
+	if d.c.Offline.Max > 0 {
+		if err = conn.Send("ZREMRANGEBYRANK", key, 0, -(d.c.Offline.Max + 1)); err != nil {
+			d.log.Error(fmt.Sprintf("conn.Send(ZREMRANGEBYRANK %s) error(%v)", key, err))
+			return
+		}
+		n++
+	}
+	if expire > 0 {
+		if err = conn.Send("EXPIRE", key, expire); err != nil {
+			d.log.Error(fmt.Sprintf("conn.Send(EXPIRE %s) error(%v)", key, err))
+			return
+		}
+		if err = conn.Send("EXPIRE", keyMidOfflineSeq(mid), expire); err != nil {
+			d.log.Error(fmt.Sprintf("conn.Send(EXPIRE %s) error(%v)", keyMidOfflineSeq(mid), err))
+			return
+		}
+		n += 2
+	}
+	if err = conn.Flush(); err != nil {
+		d.log.Error(fmt.Sprintf("conn.Flush() error(%v)", err))
+		return
+	}
+	for i := 0; i < n; i++ {
+		if _, err = conn.Receive(); err != nil {
+			d.log.Error(fmt.Sprintf("conn.Receive() error(%v)", err))
+			return
+		}
+	}
+	return
+}
+
+// Offlines get the member's offline messages from redis.
+func (d *Dao) Offlines(c context.Context, mid int64) (msgs []*model.OfflineMsg, err error) {
+	conn := d.redis.Get()
+	defer conn.Close()
+	key := keyMidOffline(mid)
+	values, err := redis.ByteSlices(conn.Do("ZRANGE", key, 0, -1))
+	if err != nil {
+		d.log.Error(fmt.Sprintf("conn.Do(ZRANGE %s) error(%v)", key, err))
+		return
+	}
+	deadline := time.Now().Add(-d.c.Offline.Expire).Unix()
+	for _, b := range values {
+		msg := new(model.OfflineMsg)
+		if err := json.Unmarshal(b, msg); err != nil {
+			d.log.Error(fmt.Sprintf("offline json.Unmarshal(%s) error(%v)", b, err))
+			continue
+		}
+		if d.c.Offline.Expire > 0 && msg.Created < deadline {
+			continue
+		}
+		msgs = append(msgs, msg)
+	}
+	return
+}
+
+// AckOffline remove acked messages from redis.
+func (d *Dao) AckOffline(c context.Context, mid int64, id int64) (err error) {
+	conn := d.redis.Get()
+	defer conn.Close()
+	key := keyMidOffline(mid)
+	if _, err = conn.Do("ZREMRANGEBYSCORE", key, "-inf", id); err != nil {
+		d.log.Error(fmt.Sprintf("conn.Do(ZREMRANGEBYSCORE %s,%d) error(%v)", key, id, err))
+	}
+	return
+}
+
+// MemoryOffline is an in-memory offline mailbox, used by tests and single node deployment.
+type MemoryOffline struct {
+	lock   sync.Mutex
+	expire time.Duration
+	max    int
+	seqs   map[int64]int64
+	boxes  map[int64][]*model.OfflineMsg
+}
+
+// NewMemoryOffline new a in-memory offline mailbox.
+func NewMemoryOffline(c *conf.Offline) *MemoryOffline {
+	return &MemoryOffline{
+		expire: c.Expire,
+		max:    c.Max,
+		seqs:   make(map[int64]int64),
+		boxes:  make(map[int64][]*model.OfflineMsg),
+	}
+}
+
+// AddOffline add a message to the member's mailbox.
+func (m *MemoryOffline) AddOffline(c context.Context, mid int64, op int32, msg []byte) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.seqs[mid]++
+	box := append(m.boxes[mid], &model.OfflineMsg{
+		ID:        m.seqs[mid],
+		Operation: op,
+		Msg:       msg,
+		Created:   time.Now().Unix(),
+	})
+	if m.max > 0 && len(box) > m.max {
+		box = box[len(box)-m.max:]
+	}
+	m.boxes[mid] = box
+	return nil
+}
+
+// Offlines get the member's unexpired messages.
+func (m *MemoryOffline) Offlines(c context.Context, mid int64) ([]*model.OfflineMsg, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	var (
+		msgs     []*model.OfflineMsg
+		deadline = time.Now().Add(-m.expire).Unix()
+	)
+	for _, msg := range m.boxes[mid] {
+		if m.expire > 0 && msg.Created < deadline {
+			continue
+		}
+		msgs = append(msgs, msg)
+	}
+	if len(msgs) == 0 {
+		delete(m.boxes, mid)
+		return nil, nil
+	}
+	m.boxes[mid] = msgs
+	return append([]*model.OfflineMsg(nil), msgs...), nil
+}
+
+// AckOffline remove acked messages of the member.
+func (m *MemoryOffline) AckOffline(c context.Context, mid int64, id int64) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	box := m.boxes[mid]
+	idx := sort.Search(len(box), func(i int) bool {
+		return box[i].ID > id
+	})
+	if idx >= len(box) {
+		delete(m.boxes, mid)
+		return nil
+	}
+	m.boxes[mid] = box[idx:]
+	return nil
+}
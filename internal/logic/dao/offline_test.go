@@ -0,0 +1,43 @@
+package dao
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-im/internal/logic/conf"
+)
+
+func TestMemoryOffline(t *testing.T) {
+	var (
+		c   = context.Background()
+		mid = int64(1)
+		m   = NewMemoryOffline(&conf.Offline{Expire: time.Hour, Max: 3})
+	)
+	for i := 0; i < 5; i++ {
+		if err := m.AddOffline(c, mid, 1000, []byte{byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	msgs, _ := m.Offlines(c, mid)
+	if len(msgs) != 3 || msgs[0].ID != 3 || msgs[2].ID != 5 {
+		t.Fatalf("cap not applied, got %d msgs", len(msgs))
+	}
+	if err := m.AckOffline(c, mid, 4); err != nil {
+		t.Fatal(err)
+	}
+	msgs, _ = m.Offlines(c, mid)
+	if len(msgs) != 1 || msgs[0].ID != 5 {
+		t.Fatalf("ack not applied, got %d msgs", len(msgs))
+	}
+	_ = m.AckOffline(c, mid, 5)
+	if msgs, _ = m.Offlines(c, mid); len(msgs) != 0 {
+		t.Fatalf("mailbox should be empty, got %d msgs", len(msgs))
+	}
+	// expired messages are dropped
+	_ = m.AddOffline(c, mid, 1000, nil)
+	m.boxes[mid][0].Created = time.Now().Add(-2 * time.Hour).Unix()
+	if msgs, _ = m.Offlines(c, mid); len(msgs) != 0 {
+		t.Fatalf("expired message not dropped, got %d msgs", len(msgs))
+	}
+}
@@ -10,14 +10,19 @@ import (
 	model "go-im/internal/logic/dto"
 	"go-im/pkg/cityhash"
 	"google.golang.org/protobuf/proto"
-	"log"
 	"strconv"
+	"strings"
+	"time"
 )
 
 const (
-	_prefixMidServer    = "mid_%d" // mid -> key:server
-	_prefixKeyServer    = "key_%s" // key -> server
-	_prefixServerOnline = "ol_%s"  // server -> online
+	_prefixMidServer    = "mid_%d"     // mid -> key:server|connect time
+	_prefixKeyServer    = "key_%s"     // key -> server
+	_prefixServerOnline = "ol_%s"      // server -> online
+	_prefixKeyRevoked   = "revoked_%s" // key -> revoked session
+
+	_onlineTotalField = "total" // server online hash field of the total counts
+	_midServerSep     = "|"     // mid hash value的server和连接时间(毫秒)的分隔符
 )
 
 func keyMidServer(mid int64) string {
@@ -32,46 +37,182 @@ func keyServerOnline(key string) string {
 	return fmt.Sprintf(_prefixServerOnline, key)
 }
 
+func keyKeyRevoked(key string) string {
+	return fmt.Sprintf(_prefixKeyRevoked, key)
+}
+
+// midServerValue the value of the mid hash, the connect time orders the keys
+// of the mid when kicking the oldest.
+func midServerValue(server string, connected time.Time) string {
+	return server + _midServerSep + strconv.FormatInt(connected.UnixNano()/int64(time.Millisecond), 10)
+}
+
+// midServer get the server of the mid hash value, the old value is the server only.
+func midServer(value string) string {
+	if i := strings.LastIndex(value, _midServerSep); i >= 0 {
+		return value[:i]
+	}
+	return value
+}
+
+// _addMappingScript add the mapping and enforce the max keys of the mid in one
+// script, the stale keys whose key_%s expired are dropped first. The reconnecting
+// key is not counted. It returns 0 if rejected, or the evicted key, server pairs.
+// KEYS: mid hash, key server. ARGV: key, server, mid hash value, expire, max, kick, key prefix.
+var _addMappingScript = redis.NewScript(2, `
+local max = tonumber(ARGV[5])
+local evicts = {}
+if max > 0 then
+	local others = {}
+	local fields = redis.call('HGETALL', KEYS[1])
+	for i = 1, #fields, 2 do
+		local k, v = fields[i], fields[i + 1]
+		if k ~= ARGV[1] then
+			if redis.call('EXISTS', ARGV[7] .. k) == 1 then
+				others[#others + 1] = {k, v, tonumber(string.match(v, '|(%d+)$')) or 0}
+			else
+				redis.call('HDEL', KEYS[1], k)
+			end
+		end
+	end
+	if #others >= max then
+		if ARGV[6] ~= '1' then
+			return 0
+		end
+		table.sort(others, function(a, b) return a[3] < b[3] end)
+		for i = 1, #others - max + 1 do
+			local k, v = others[i][1], others[i][2]
+			redis.call('HDEL', KEYS[1], k)
+			redis.call('DEL', ARGV[7] .. k)
+			evicts[#evicts + 1] = k
+			evicts[#evicts + 1] = string.match(v, '^(.*)|%d+$') or v
+		end
+	end
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[3])
+redis.call('EXPIRE', KEYS[1], ARGV[4])
+redis.call('SET', KEYS[2], ARGV[2], 'EX', ARGV[4])
+return evicts`)
+
 // AddMapping add a mapping.
 // Mapping:
 //	mid -> key_server
 //	key -> server
 func (d *Dao) AddMapping(c context.Context, mid int64, key, server string) (err error) {
+	_, _, err = d.AddMappingLimit(c, mid, key, server, 0, false)
+	return
+}
+
+// AddMappingLimit add a mapping if the mid has less than max keys, 0 not limited.
+// Over the limit ok is false, or the oldest keys are evicted if kick is true,
+// evicts is the evicted key -> server.
+func (d *Dao) AddMappingLimit(c context.Context, mid int64, key, server string, max int, kick bool) (evicts map[string]string, ok bool, err error) {
 	conn := d.redis.Get()
 	defer conn.Close()
-	if _, err = conn.Do("HSET", keyMidServer(mid), key, server); err != nil {
-		log.Fatalf("conn.Send(HSET %d,%s,%s) error(%v)", mid, server, key, err)
+	reply, err := _addMappingScript.Do(conn, keyMidServer(mid), keyKeyServer(key),
+		key, server, midServerValue(server, time.Now()), d.redisExpire, max, kick, fmt.Sprintf(_prefixKeyServer, ""))
+	if err != nil {
+		d.log.Error(fmt.Sprintf("conn.Do(EVAL %d,%s,%s) error(%v)", mid, key, server, err))
+		return
+	}
+	if _, rejected := reply.(int64); rejected {
+		return
+	}
+	pairs, err := redis.StringMap(reply, nil)
+	if err != nil {
+		d.log.Error(fmt.Sprintf("redis.StringMap(%d,%s) error(%v)", mid, key, err))
 		return
 	}
-	if _, err = conn.Do("EXPIRE", keyMidServer(mid), d.redisExpire); err != nil {
-		log.Fatalf("conn.Send(EXPIRE %d,%s,%s) error(%v)", mid, key, server, err)
+	return pairs, true, nil
+}
+
+// ExpireMapping expire a mapping, has is false if the mapping not exist.
+func (d *Dao) ExpireMapping(c context.Context, mid int64, key string) (has bool, err error) {
+	conn := d.redis.Get()
+	defer conn.Close()
+	if err = conn.Send("EXPIRE", keyMidServer(mid), d.redisExpire); err != nil {
+		d.log.Error(fmt.Sprintf("conn.Send(EXPIRE %d,%s) error(%v)", mid, key, err))
 		return
 	}
-	if _, err = conn.Do("SET", keyKeyServer(key), server); err != nil {
-		log.Fatalf("conn.Send(HSET %d,%s,%s) error(%v)", mid, server, key, err)
+	if err = conn.Send("EXPIRE", keyKeyServer(key), d.redisExpire); err != nil {
+		d.log.Error(fmt.Sprintf("conn.Send(EXPIRE %d,%s) error(%v)", mid, key, err))
 		return
 	}
-	if _, err = conn.Do("EXPIRE", keyKeyServer(key), d.redisExpire); err != nil {
-		log.Fatalf("conn.Send(EXPIRE %d,%s,%s) error(%v)", mid, key, server, err)
+	if err = conn.Flush(); err != nil {
+		d.log.Error(fmt.Sprintf("conn.Flush() error(%v)", err))
 		return
 	}
+	has = true
+	for i := 0; i < 2; i++ {
+		var ok bool
+		if ok, err = redis.Bool(conn.Receive()); err != nil {
+			d.log.Error(fmt.Sprintf("conn.Receive() error(%v)", err))
+			return
+		}
+		has = has && ok
+	}
 	return
 }
 
-// DelMapping del a mapping.
+// RevokeKey revoke a session, its mapping won't be renewed by heartbeat.
+func (d *Dao) RevokeKey(c context.Context, key string) (err error) {
+	conn := d.redis.Get()
+	defer conn.Close()
+	if _, err = conn.Do("SETEX", keyKeyRevoked(key), d.redisExpire, 1); err != nil {
+		d.log.Error(fmt.Sprintf("conn.Do(SETEX %s) error(%v)", keyKeyRevoked(key), err))
+	}
+	return
+}
+
+// Revoked reports whether the session is revoked.
+func (d *Dao) Revoked(c context.Context, key string) (revoked bool, err error) {
+	conn := d.redis.Get()
+	defer conn.Close()
+	if revoked, err = redis.Bool(conn.Do("EXISTS", keyKeyRevoked(key))); err != nil {
+		d.log.Error(fmt.Sprintf("conn.Do(EXISTS %s) error(%v)", keyKeyRevoked(key), err))
+	}
+	return
+}
+
+// _delMappingScript delete the mapping only if it's still on the server, the
+// client may have reconnected to another comet with the same key. It returns
+// 1 if the key mapping is deleted.
+var _delMappingScript = redis.NewScript(2, `
+local v = redis.call('HGET', KEYS[1], ARGV[1])
+if v == ARGV[2] or (v and string.sub(v, 1, #ARGV[2] + 1) == ARGV[2] .. '|') then redis.call('HDEL', KEYS[1], ARGV[1]) end
+if redis.call('GET', KEYS[2]) == ARGV[2] then return redis.call('DEL', KEYS[2]) end
+return 0`)
+
+// DelMapping del a mapping if it's still on the server, has is false if not.
 func (d *Dao) DelMapping(c context.Context, mid int64, key, server string) (has bool, err error) {
 	conn := d.redis.Get()
 	defer conn.Close()
-	if _, err = conn.Do("HDEL", keyMidServer(mid), key); err != nil {
-		log.Fatalf("conn.Send(HDEL %d,%s,%s) error(%v)", mid, key, server, err)
-		return
+	if has, err = redis.Bool(_delMappingScript.Do(conn, keyMidServer(mid), keyKeyServer(key), key, server)); err != nil {
+		d.log.Error(fmt.Sprintf("conn.Do(EVAL %d,%s,%s) error(%v)", mid, key, server, err))
 	}
+	return
+}
 
-	if _, err = conn.Do("DEL", keyKeyServer(key)); err != nil {
-		log.Fatalf("conn.Send(HDEL %d,%s,%s) error(%v)", mid, key, server, err)
+// DelMappings delete the mappings of many conns of the server in one pipeline.
+func (d *Dao) DelMappings(c context.Context, server string, conns []*pb.Conn) (err error) {
+	conn := d.redis.Get()
+	defer conn.Close()
+	for _, cn := range conns {
+		if err = _delMappingScript.Send(conn, keyMidServer(cn.Mid), keyKeyServer(cn.Key), cn.Key, server); err != nil {
+			d.log.Error(fmt.Sprintf("conn.Send(EVAL %d,%s,%s) error(%v)", cn.Mid, cn.Key, server, err))
+			return
+		}
+	}
+	if err = conn.Flush(); err != nil {
+		d.log.Error(fmt.Sprintf("conn.Flush() error(%v)", err))
 		return
 	}
-
+	for range conns {
+		if _, err = conn.Receive(); err != nil {
+			d.log.Error(fmt.Sprintf("conn.Receive() error(%v)", err))
+			return
+		}
+	}
 	return
 }
 
@@ -87,6 +228,11 @@ func (d *Dao) AddServerOnline(c context.Context, server string, online *model.On
 		rMap[room] = count
 	}
 	key := keyServerOnline(server)
+	//总连接数单独保存, 没有房间时也能刷新updated
+	total := &model.Online{Server: online.Server, IPCount: online.IPCount, ConnCount: online.ConnCount, Updated: online.Updated}
+	if err = d.addServerOnline(c, key, _onlineTotalField, total); err != nil {
+		return
+	}
 	//map[13:map[xxtttdig:15] 19:map[ss3indig:23] 27:map[3idd2ndig:2 xx3indig:22]]
 	for hashKey, value := range roomsMap {
 		err = d.addServerOnline(c, key, strconv.FormatInt(int64(hashKey), 10), &model.Online{RoomCount: value, Server: online.Server, Updated: online.Updated})
@@ -104,20 +250,20 @@ func (d *Dao) addServerOnline(c context.Context, key string, hashKey string, onl
 	//redis管道 可以通过send方法一次性向服务器发送一个命令或者多个命令，命令发送完毕后
 	//用flush方法将缓冲区的命令一次性的发送到服务端，客户端用receive的方法读取命令的结果
 	if err = conn.Send("HSET", key, hashKey, b); err != nil {
-		log.Fatalf("conn.Send(SET %s,%s) error(%v)", key, hashKey, err)
+		d.log.Error(fmt.Sprintf("conn.Send(SET %s,%s) error(%v)", key, hashKey, err))
 		return
 	}
 	if err = conn.Send("EXPIRE", key, d.redisExpire); err != nil {
-		log.Fatalf("conn.Send(EXPIRE %s) error(%v)", key, err)
+		d.log.Error(fmt.Sprintf("conn.Send(EXPIRE %s) error(%v)", key, err))
 		return
 	}
 	if err = conn.Flush(); err != nil {
-		log.Fatalf("conn.Flush() error(%v)", err)
+		d.log.Error(fmt.Sprintf("conn.Flush() error(%v)", err))
 		return
 	}
 	for i := 0; i < 2; i++ {
 		if _, err = conn.Receive(); err != nil {
-			log.Fatalf("conn.Receive() error(%v)", err)
+			d.log.Error(fmt.Sprintf("conn.Receive() error(%v)", err))
 			return
 		}
 	}
@@ -125,38 +271,42 @@ func (d *Dao) addServerOnline(c context.Context, key string, hashKey string, onl
 }
 
 // ServerOnline get a server online.
+// Only the shards written by the latest renew are merged, the rooms of the
+// shards not rewritten are gone.
 func (d *Dao) ServerOnline(c context.Context, server string) (online *model.Online, err error) {
 	online = &model.Online{RoomCount: map[string]int32{}}
-	key := keyServerOnline(server)
-	for i := 0; i < 64; i++ {
-		ol, err := d.serverOnline(c, key, strconv.FormatInt(int64(i), 10))
-		if err == nil && ol != nil {
-			online.Server = ol.Server
-			if ol.Updated > online.Updated {
-				online.Updated = ol.Updated
-			}
-			for room, count := range ol.RoomCount {
-				online.RoomCount[room] = count
-			}
-		}
-	}
-	return
-}
-
-func (d *Dao) serverOnline(c context.Context, key string, hashKey string) (online *model.Online, err error) {
 	conn := d.redis.Get()
 	defer conn.Close()
-	b, err := redis.Bytes(conn.Do("HGET", key, hashKey))
+	key := keyServerOnline(server)
+	values, err := redis.StringMap(conn.Do("HGETALL", key))
 	if err != nil {
-		if err != redis.ErrNil {
-			d.log.Error(fmt.Sprintf("conn.Do(HGET %s %s) error(%v)", key, hashKey, err))
-		}
+		d.log.Error(fmt.Sprintf("conn.Do(HGETALL %s) error(%v)", key, err))
 		return
 	}
-	online = new(model.Online)
-	if err = json.Unmarshal(b, online); err != nil {
-		d.log.Error(fmt.Sprintf("serverOnline json.Unmarshal(%s) error(%v)", b, err))
-		return
+	shards := make([]*model.Online, 0, len(values))
+	for hashKey, b := range values {
+		ol := new(model.Online)
+		if err := json.Unmarshal([]byte(b), ol); err != nil {
+			d.log.Error(fmt.Sprintf("serverOnline json.Unmarshal(%s) error(%v)", b, err))
+			continue
+		}
+		if hashKey == _onlineTotalField {
+			online.IPCount = ol.IPCount
+			online.ConnCount = ol.ConnCount
+		}
+		online.Server = ol.Server
+		if ol.Updated > online.Updated {
+			online.Updated = ol.Updated
+		}
+		shards = append(shards, ol)
+	}
+	for _, ol := range shards {
+		if ol.Updated < online.Updated {
+			continue
+		}
+		for room, count := range ol.RoomCount {
+			online.RoomCount[room] = count
+		}
 	}
 	return
 }
@@ -181,19 +331,21 @@ func (d *Dao) ServersByKeys(c context.Context, keys []string) (res []string, err
 		args = append(args, keyKeyServer(key))
 	}
 	if res, err = redis.Strings(conn.Do("MGET", args...)); err != nil {
-		log.Fatalf("conn.Do(MGET %v) error(%v)", args, err)
+		d.log.Error(fmt.Sprintf("conn.Do(MGET %v) error(%v)", args, err))
 	}
 	return
 }
 
 // PushMsg push a message to databus.
-func (d *Dao) PushMsg(c context.Context, op int32, server string, keys []string, msg []byte) (err error) {
+func (d *Dao) PushMsg(c context.Context, op int32, server string, keys []string, msg []byte, codec int32) (err error) {
 	pushMsg := &pb.PushMsg{
 		Type:      pb.PushMsg_PUSH,
 		Operation: op,
 		Server:    server,
 		Keys:      keys,
 		Msg:       msg,
+		Codec:     codec,
+		MsgId:     newMsgID(),
 	}
 	b, err := proto.Marshal(pushMsg)
 	if err != nil {
@@ -204,8 +356,8 @@ func (d *Dao) PushMsg(c context.Context, op int32, server string, keys []string,
 		Topic: d.c.Kafka.Topic,
 		Value: sarama.ByteEncoder(b),
 	}
-	if _, _, err = d.kafkaPub.SendMessage(m); err != nil {
-		log.Fatalf("PushMsg.send(push pushMsg:%v) error(%v)", pushMsg, err)
+	if _, _, err = d.sendMessage(c, "push", pushMsg.MsgId, m); err != nil {
+		d.log.Error(fmt.Sprintf("PushMsg.send(push pushMsg:%v) error(%v)", pushMsg, err))
 	}
 	return
 }
@@ -217,12 +369,12 @@ func (d *Dao) KeysByMids(c context.Context, mids []int64) (ress map[string]strin
 	ress = make(map[string]string)
 	for _, mid := range mids { //mid key server
 		if err = conn.Send("HGETALL", keyMidServer(mid)); err != nil {
-			log.Fatalf("conn.Do(HGETALL %d) error(%v)", mid, err)
+			d.log.Error(fmt.Sprintf("conn.Do(HGETALL %d) error(%v)", mid, err))
 			return
 		}
 	}
 	if err = conn.Flush(); err != nil {
-		log.Fatalf("conn.Flush() error(%v)", err)
+		d.log.Error(fmt.Sprintf("conn.Flush() error(%v)", err))
 		return
 	}
 	for idx := 0; idx < len(mids); idx++ {
@@ -230,25 +382,27 @@ func (d *Dao) KeysByMids(c context.Context, mids []int64) (ress map[string]strin
 			res map[string]string
 		)
 		if res, err = redis.StringMap(conn.Receive()); err != nil {
-			log.Fatalf("conn.Receive() error(%v)", err)
+			d.log.Error(fmt.Sprintf("conn.Receive() error(%v)", err))
 			return
 		}
 		if len(res) > 0 {
 			olMids = append(olMids, mids[idx])
 		}
 		for k, v := range res {
-			ress[k] = v
+			ress[k] = midServer(v)
 		}
 	}
 	return
 }
 
-func (d *Dao) BroadcastRoomMsg(c context.Context, op int32, room string, msg []byte) error {
+func (d *Dao) BroadcastRoomMsg(c context.Context, op int32, room string, msg []byte, codec int32) error {
 	pushMsg := &pb.PushMsg{
 		Type:      pb.PushMsg_ROOM,
 		Operation: op,
 		Room:      room,
 		Msg:       msg,
+		Codec:     codec,
+		MsgId:     newMsgID(),
 	}
 	b, err := proto.Marshal(pushMsg)
 	if err != nil {
@@ -260,18 +414,20 @@ func (d *Dao) BroadcastRoomMsg(c context.Context, op int32, room string, msg []b
 		Topic: d.c.Kafka.Topic,
 		Value: sarama.ByteEncoder(b),
 	}
-	if _, _, err = d.kafkaPub.SendMessage(m); err != nil {
-		log.Fatalf("PushMsg.send(broadcast_room pushMsg:%v) error(%v)", pushMsg, err)
+	if _, _, err = d.sendMessage(c, "room", pushMsg.MsgId, m); err != nil {
+		d.log.Error(fmt.Sprintf("PushMsg.send(broadcast_room pushMsg:%v) error(%v)", pushMsg, err))
 	}
-	return nil
+	return err
 }
 
-func (d *Dao) BroadcastMsg(c context.Context, op, speed int32, msg []byte) error {
+func (d *Dao) BroadcastMsg(c context.Context, op, speed int32, msg []byte, codec int32) error {
 	pushMsg := &pb.PushMsg{
 		Type:      pb.PushMsg_BROADCAST,
 		Operation: op,
 		Speed:     speed,
 		Msg:       msg,
+		Codec:     codec,
+		MsgId:     newMsgID(),
 	}
 	b, err := proto.Marshal(pushMsg)
 	if err != nil {
@@ -283,8 +439,8 @@ func (d *Dao) BroadcastMsg(c context.Context, op, speed int32, msg []byte) error
 		Topic: d.c.Kafka.Topic,
 		Value: sarama.ByteEncoder(b),
 	}
-	if _, _, err = d.kafkaPub.SendMessage(m); err != nil {
-		log.Fatalf("PushMsg.send(broadcast_room pushMsg:%v) error(%v)", pushMsg, err)
+	if _, _, err = d.sendMessage(c, "broadcast", pushMsg.MsgId, m); err != nil {
+		d.log.Error(fmt.Sprintf("PushMsg.send(broadcast pushMsg:%v) error(%v)", pushMsg, err))
 	}
-	return nil
+	return err
 }
@@ -0,0 +1,139 @@
+package dao
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"go-im/internal/logic/conf"
+	"go-im/pkg/log"
+)
+
+// newTestDao new a dao on the redis of GOIM_TEST_REDIS or the local one, the
+// test is skipped if the redis is not reachable.
+func newTestDao(t *testing.T) *Dao {
+	addr := os.Getenv("GOIM_TEST_REDIS")
+	if addr == "" {
+		addr = "127.0.0.1:6379"
+	}
+	d := &Dao{
+		redis: newRedis(&conf.Redis{
+			Network:     "tcp",
+			Addr:        addr,
+			Idle:        1,
+			DialTimeout: time.Second,
+		}),
+		redisExpire: 60,
+		log:         log.NewLog("im", true),
+	}
+	conn := d.redis.Get()
+	defer conn.Close()
+	if _, err := conn.Do("PING"); err != nil {
+		t.Skipf("redis %s not reachable: %v", addr, err)
+	}
+	t.Cleanup(func() { _ = d.Close() })
+	return d
+}
+
+func TestMidServer(t *testing.T) {
+	at := time.Unix(1700000000, 123e6)
+	v := midServerValue("comet|1", at)
+	if v != "comet|1|1700000000123" {
+		t.Fatalf("midServerValue %s", v)
+	}
+	for value, want := range map[string]string{
+		v:         "comet|1",
+		"comet-2": "comet-2",
+	} {
+		if got := midServer(value); got != want {
+			t.Fatalf("midServer(%s) %s want %s", value, got, want)
+		}
+	}
+}
+
+func TestAddMappingLimit(t *testing.T) {
+	var (
+		d   = newTestDao(t)
+		c   = context.Background()
+		mid = time.Now().UnixNano()
+	)
+	key := func(i int) string { return strconv.FormatInt(mid, 10) + "_" + strconv.Itoa(i) }
+	t.Cleanup(func() {
+		conn := d.redis.Get()
+		defer conn.Close()
+		_, _ = conn.Do("DEL", keyMidServer(mid), keyKeyServer(key(1)), keyKeyServer(key(2)), keyKeyServer(key(3)))
+	})
+	add := func(i int, server string, max int, kick bool) (map[string]string, bool) {
+		t.Helper()
+		evicts, ok, err := d.AddMappingLimit(c, mid, key(i), server, max, kick)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the connect time orders the keys in milliseconds
+		time.Sleep(2 * time.Millisecond)
+		return evicts, ok
+	}
+	if _, ok := add(1, "s1", 2, false); !ok {
+		t.Fatal("key 1 rejected")
+	}
+	if _, ok := add(2, "s2", 2, false); !ok {
+		t.Fatal("key 2 rejected")
+	}
+	// reject policy
+	if _, ok := add(3, "s3", 2, false); ok {
+		t.Fatal("key 3 not rejected")
+	}
+	// the reconnecting key is not counted
+	if _, ok := add(1, "s1", 2, false); !ok {
+		t.Fatal("reconnecting key 1 rejected")
+	}
+	// kick policy evicts the oldest key
+	evicts, ok := add(3, "s3", 2, true)
+	if !ok || len(evicts) != 1 || evicts[key(2)] != "s2" {
+		t.Fatalf("kick evicts %v ok %v", evicts, ok)
+	}
+	conn := d.redis.Get()
+	defer conn.Close()
+	if n, _ := redis.Int(conn.Do("EXISTS", keyKeyServer(key(2)))); n != 0 {
+		t.Fatal("evicted key mapping not deleted")
+	}
+	if n, _ := redis.Int(conn.Do("HLEN", keyMidServer(mid))); n != 2 {
+		t.Fatalf("mid keys %d", n)
+	}
+	// the stale keys whose key mapping expired are not counted
+	if _, err := conn.Do("DEL", keyKeyServer(key(3))); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := add(2, "s2", 2, false); !ok {
+		t.Fatal("key 2 rejected by the stale key")
+	}
+
+	// the mapping moved to another server is not deleted by the old one
+	if _, ok := add(1, "s4", 0, false); !ok {
+		t.Fatal("key 1 not moved")
+	}
+	if has, err := d.DelMapping(c, mid, key(1), "s1"); err != nil || has {
+		t.Fatalf("deleted by the old server %v %v", has, err)
+	}
+	if has, err := d.DelMapping(c, mid, key(1), "s4"); err != nil || !has {
+		t.Fatalf("not deleted by its server %v %v", has, err)
+	}
+}
+
+func TestMappingRedisDown(t *testing.T) {
+	// the admin kick looks up the mappings, a redis error is returned to the caller
+	d := &Dao{
+		redis: newRedis(&conf.Redis{Network: "tcp", Addr: "127.0.0.1:1", DialTimeout: time.Second}),
+		log:   log.NewLog("im", true),
+	}
+	defer d.Close()
+	if _, err := d.ServersByKeys(context.Background(), []string{"a"}); err == nil {
+		t.Fatal("ServersByKeys no error")
+	}
+	if _, _, err := d.KeysByMids(context.Background(), []int64{1}); err == nil {
+		t.Fatal("KeysByMids no error")
+	}
+}
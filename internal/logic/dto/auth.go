@@ -0,0 +1,24 @@
+package dto
+
+// PlatformWeb is the platform of browsers, which connect to the comets by domain.
+const PlatformWeb = "web"
+
+// Identity the member authenticated by a token.
+type Identity struct {
+	Mid      int64    `json:"mid"`
+	Platform string   `json:"platform"`
+	Rooms    []string `json:"rooms"` // rooms allowed to join, empty means no limit
+}
+
+// AllowRoom reports whether the member may join the room.
+func (i *Identity) AllowRoom(room string) bool {
+	if room == "" || len(i.Rooms) == 0 {
+		return true
+	}
+	for _, r := range i.Rooms {
+		if r == room {
+			return true
+		}
+	}
+	return false
+}
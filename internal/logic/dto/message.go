@@ -0,0 +1,32 @@
+package dto
+
+import "encoding/json"
+
+const (
+	// MsgTypePeer send to a member.
+	MsgTypePeer = "peer"
+	// MsgTypeRoom send to a room.
+	MsgTypeRoom = "room"
+	// MsgTypeGroup send to all members of a group.
+	MsgTypeGroup = "group"
+)
+
+// Envelope the upstream message sent by client in OpSendMsg.
+type Envelope struct {
+	Type  string          `json:"type"`
+	Op    int32           `json:"op"`
+	ToMid int64           `json:"to_mid,omitempty"`
+	Room  string          `json:"room,omitempty"`
+	Group string          `json:"group,omitempty"`
+	Msg   json.RawMessage `json:"msg"`
+}
+
+// Message the message delivered to the receivers.
+type Message struct {
+	From  int64           `json:"from"`
+	Type  string          `json:"type"`
+	ToMid int64           `json:"to_mid,omitempty"`
+	Room  string          `json:"room,omitempty"`
+	Group string          `json:"group,omitempty"`
+	Msg   json.RawMessage `json:"msg"`
+}
@@ -0,0 +1,9 @@
+package dto
+
+// OfflineMsg a message kept in member's offline mailbox.
+type OfflineMsg struct {
+	ID        int64  `json:"id"`
+	Operation int32  `json:"op"`
+	Msg       []byte `json:"msg"`
+	Created   int64  `json:"created"`
+}
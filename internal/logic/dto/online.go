@@ -4,6 +4,8 @@ package dto
 type Online struct {
 	Server    string           `json:"server"`
 	RoomCount map[string]int32 `json:"room_count"`
+	IPCount   int32            `json:"ip_count,omitempty"`
+	ConnCount int32            `json:"conn_count,omitempty"`
 	Updated   int64            `json:"updated"`
 }
 
@@ -6,7 +6,9 @@ import (
 	"go-im/internal/logic"
 	"go-im/internal/logic/conf"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
 	"net"
 )
 
@@ -38,11 +40,20 @@ type server struct {
 }
 
 func (s server) Connect(ctx context.Context, req *pb.ConnectReq) (*pb.ConnectReply, error) {
-	mid, key, room, accepts, hb, err := s.logic.Connect(ctx, req.Server, req.Cookie, req.Token)
+	mid, key, room, accepts, rooms, hb, err := s.logic.Connect(ctx, req.Server, req.Cookie, req.Token)
+	if err == logic.ErrAuthFailed || err == logic.ErrRoomNotAllowed {
+		return &pb.ConnectReply{}, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err == logic.ErrTooManyConns {
+		return &pb.ConnectReply{}, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	if err == logic.ErrBanned {
+		return &pb.ConnectReply{}, status.Error(codes.PermissionDenied, err.Error())
+	}
 	if err != nil {
 		return &pb.ConnectReply{}, err
 	}
-	return &pb.ConnectReply{Mid: mid, Key: key, RoomID: room, Accepts: accepts, Heartbeat: hb}, nil
+	return &pb.ConnectReply{Mid: mid, Key: key, RoomID: room, Accepts: accepts, Heartbeat: hb, Allow: s.logic.Allow(), Rooms: rooms}, nil
 }
 
 func (s server) Disconnect(ctx context.Context, req *pb.DisconnectReq) (*pb.DisconnectReply, error) {
@@ -53,13 +64,23 @@ func (s server) Disconnect(ctx context.Context, req *pb.DisconnectReq) (*pb.Disc
 	return &pb.DisconnectReply{Has: has}, nil
 }
 
+func (s server) DisconnectBatch(ctx context.Context, req *pb.DisconnectBatchReq) (*pb.DisconnectBatchReply, error) {
+	if err := s.logic.DisconnectBatch(ctx, req.Server, req.Conns); err != nil {
+		return &pb.DisconnectBatchReply{}, err
+	}
+	return &pb.DisconnectBatchReply{}, nil
+}
+
 func (s server) Heartbeat(ctx context.Context, req *pb.HeartbeatReq) (*pb.HeartbeatReply, error) {
-	//TODO implement me
-	panic("implement me")
+	revoked, err := s.logic.Heartbeat(ctx, req.Mid, req.Key, req.Server, req.Registered)
+	if err != nil {
+		return &pb.HeartbeatReply{}, err
+	}
+	return &pb.HeartbeatReply{Revoked: revoked}, nil
 }
 
 func (s server) RenewOnline(ctx context.Context, req *pb.OnlineReq) (*pb.OnlineReply, error) {
-	allRoomCount, err := s.logic.RenewOnline(ctx, req.Server, req.RoomCount)
+	allRoomCount, err := s.logic.RenewOnline(ctx, req.Server, req.RoomCount, req.IpCount, req.ConnCount)
 	if err != nil {
 		return &pb.OnlineReply{}, err
 	}
@@ -67,13 +88,21 @@ func (s server) RenewOnline(ctx context.Context, req *pb.OnlineReq) (*pb.OnlineR
 }
 
 func (s server) Receive(ctx context.Context, req *pb.ReceiveReq) (*pb.ReceiveReply, error) {
-	//TODO implement me
-	panic("implement me")
+	err := s.logic.Receive(ctx, req.Mid, req.Rooms, req.Proto)
+	switch err {
+	case nil:
+	case logic.ErrInvalidMsg:
+		return &pb.ReceiveReply{}, status.Error(codes.InvalidArgument, err.Error())
+	case logic.ErrNotAllowed:
+		return &pb.ReceiveReply{}, status.Error(codes.PermissionDenied, err.Error())
+	default:
+		return &pb.ReceiveReply{}, err
+	}
+	return &pb.ReceiveReply{}, nil
 }
 
 func (s server) Nodes(ctx context.Context, req *pb.NodesReq) (*pb.NodesReply, error) {
-	//TODO implement me
-	panic("implement me")
+	return s.logic.NodesWeighted(ctx, req.Platform, req.ClientIP), nil
 }
 
 var _ pb.LogicServer = &server{}
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// _defaultKickReason is sent to the clients if the reason is empty.
+const _defaultKickReason = "kicked"
+
+func (s *Server) kickKeys(c *gin.Context) {
+	var arg struct {
+		Keys   []string `form:"keys" binding:"required"`
+		Reason string   `form:"reason"`
+	}
+	if err := c.BindQuery(&arg); err != nil {
+		errors(c, RequestErr, err.Error())
+		return
+	}
+	if arg.Reason == "" {
+		arg.Reason = _defaultKickReason
+	}
+	if err := s.logic.KickKeys(c.Request.Context(), arg.Keys, arg.Reason); err != nil {
+		errors(c, ServerErr, err.Error())
+		return
+	}
+	result(c, nil, OK)
+}
+
+func (s *Server) kickMids(c *gin.Context) {
+	var arg struct {
+		Mids   []int64 `form:"mids" binding:"required"`
+		Reason string  `form:"reason"`
+		Ban    int64   `form:"ban"` //禁止重连的秒数, 0不禁止
+	}
+	if err := c.BindQuery(&arg); err != nil {
+		errors(c, RequestErr, err.Error())
+		return
+	}
+	if arg.Reason == "" {
+		arg.Reason = _defaultKickReason
+	}
+	ban := time.Duration(arg.Ban) * time.Second
+	if err := s.logic.KickMids(c.Request.Context(), arg.Mids, arg.Reason, ban); err != nil {
+		errors(c, ServerErr, err.Error())
+		return
+	}
+	result(c, nil, OK)
+}
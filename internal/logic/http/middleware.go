@@ -1,13 +1,20 @@
 package http
 
 import (
+	"crypto/subtle"
 	"fmt"
+	"net/http"
 	"net/http/httputil"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	log "github.com/golang/glog"
+	"go-im/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func loggerHandler(c *gin.Context) {
@@ -32,6 +39,41 @@ func loggerHandler(c *gin.Context) {
 	log.Infof("METHOD:%s | PATH:%s | CODE:%d | IP:%s | TIME:%d | ECODE:%d", method, path, statusCode, clientIP, latency/time.Millisecond, ecode)
 }
 
+// traceHandler start a server span of the request, as the child of the trace
+// context in the headers. Handlers get it by c.Request.Context().
+func traceHandler(c *gin.Context) {
+	route := c.FullPath()
+	if route == "" {
+		route = c.Request.URL.Path
+	}
+	ctx := tracing.ExtractHTTP(c.Request.Context(), c.Request.Header)
+	ctx, span := tracing.Tracer().Start(ctx, c.Request.Method+" "+route, trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+	c.Request = c.Request.WithContext(ctx)
+
+	c.Next()
+
+	span.SetAttributes(attribute.Int("http.status_code", c.Writer.Status()))
+	if ecode := c.GetInt(contextErrCode); ecode != 0 {
+		span.SetStatus(codes.Error, fmt.Sprintf("ecode %d", ecode))
+	}
+}
+
+// adminHandler check the "Authorization: Bearer <token>" header of the admin
+// api, all the requests are refused if the token is empty.
+func adminHandler(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		auth := c.GetHeader("Authorization")
+		if token == "" || !strings.HasPrefix(auth, "Bearer ") ||
+			subtle.ConstantTimeCompare([]byte(auth[len("Bearer "):]), []byte(token)) != 1 {
+			c.Set(contextErrCode, AuthErr)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, resp{Code: AuthErr, Message: "unauthorized"})
+			return
+		}
+		c.Next()
+	}
+}
+
 func recoverHandler(c *gin.Context) {
 	defer func() {
 		if err := recover(); err != nil {
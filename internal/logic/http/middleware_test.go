@@ -0,0 +1,35 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAdminHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, c := range []struct {
+		token, auth string
+		status      int
+	}{
+		{"secret", "Bearer secret", http.StatusOK},
+		{"secret", "Bearer wrong", http.StatusUnauthorized},
+		{"secret", "secret", http.StatusUnauthorized},
+		{"secret", "", http.StatusUnauthorized},
+		{"", "Bearer ", http.StatusUnauthorized},
+	} {
+		engine := gin.New()
+		engine.POST("/kick", adminHandler(c.token), func(c *gin.Context) { c.Status(http.StatusOK) })
+		req := httptest.NewRequest(http.MethodPost, "/kick", nil)
+		if c.auth != "" {
+			req.Header.Set("Authorization", c.auth)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Code != c.status {
+			t.Fatalf("token %q auth %q got %d want %d", c.token, c.auth, w.Code, c.status)
+		}
+	}
+}
@@ -1,27 +1,37 @@
 package http
 
 import (
-	"context"
 	"github.com/gin-gonic/gin"
+	"go-im/pkg/proto"
 	"io/ioutil"
 )
 
+// readMsg read the message body, compress is the codec name if it's pre-compressed.
+func readMsg(c *gin.Context, compress string) (msg []byte, codec proto.Codec, err error) {
+	if codec, err = proto.ParseCodec(compress); err != nil {
+		return
+	}
+	msg, err = ioutil.ReadAll(c.Request.Body)
+	return
+}
+
 func (s *Server) pushKeys(c *gin.Context) {
 	var arg struct {
-		Op   int32    `form:"operation"` //消息类型
-		Keys []string `form:"keys"`
+		Op       int32    `form:"operation"` //消息类型
+		Keys     []string `form:"keys"`
+		Compress string   `form:"compress"` //消息已预压缩 gzip/zstd/snappy
 	}
 	if err := c.BindQuery(&arg); err != nil {
 		errors(c, RequestErr, err.Error())
 		return
 	}
 	// read message
-	msg, err := ioutil.ReadAll(c.Request.Body)
+	msg, codec, err := readMsg(c, arg.Compress)
 	if err != nil {
 		errors(c, RequestErr, err.Error())
 		return
 	}
-	if err = s.logic.PushKeys(context.TODO(), arg.Op, arg.Keys, msg); err != nil {
+	if err = s.logic.PushKeys(c.Request.Context(), arg.Op, arg.Keys, msg, codec); err != nil {
 		result(c, nil, RequestErr)
 		return
 	}
@@ -30,22 +40,23 @@ func (s *Server) pushKeys(c *gin.Context) {
 
 func (s *Server) pushMids(c *gin.Context) {
 	var arg struct {
-		Op   int32   `form:"operation"`
-		Mids []int64 `form:"mids"`
+		Op       int32   `form:"operation"`
+		Mids     []int64 `form:"mids"`
+		Compress string  `form:"compress"`
 	}
 	if err := c.BindQuery(&arg); err != nil {
 		errors(c, RequestErr, err.Error())
 		return
 	}
 	// read message
-	msg, err := ioutil.ReadAll(c.Request.Body)
+	msg, codec, err := readMsg(c, arg.Compress)
 	if err != nil {
 		errors(c, RequestErr, err.Error())
 		return
 	}
 	//todo token校验
 	//发送消息
-	if err = s.logic.PushMids(context.TODO(), arg.Op, arg.Mids, msg); err != nil {
+	if err = s.logic.PushMids(c.Request.Context(), arg.Op, arg.Mids, msg, codec); err != nil {
 		errors(c, ServerErr, err.Error())
 		return
 	}
@@ -54,16 +65,17 @@ func (s *Server) pushMids(c *gin.Context) {
 
 func (s *Server) pushRoom(c *gin.Context) {
 	var arg struct {
-		Op   int32  `form:"operation" binding:"required"`
-		Type string `form:"type" binding:"required"`
-		Room string `form:"room" binding:"required"`
+		Op       int32  `form:"operation" binding:"required"`
+		Type     string `form:"type" binding:"required"`
+		Room     string `form:"room" binding:"required"`
+		Compress string `form:"compress"`
 	}
 	if err := c.BindQuery(&arg); err != nil {
 		errors(c, RequestErr, err.Error())
 		return
 	}
 	// read message
-	msg, err := ioutil.ReadAll(c.Request.Body)
+	msg, codec, err := readMsg(c, arg.Compress)
 	if err != nil {
 		errors(c, RequestErr, err.Error())
 		return
@@ -72,7 +84,7 @@ func (s *Server) pushRoom(c *gin.Context) {
 	//todo token校验
 
 	//发送消息
-	if err = s.logic.PushRoom(c, arg.Op, arg.Type, arg.Room, msg); err != nil {
+	if err = s.logic.PushRoom(c.Request.Context(), arg.Op, arg.Type, arg.Room, msg, codec); err != nil {
 		errors(c, ServerErr, err.Error())
 		return
 	}
@@ -81,20 +93,21 @@ func (s *Server) pushRoom(c *gin.Context) {
 
 func (s *Server) pushAll(c *gin.Context) {
 	var arg struct {
-		Op    int32 `form:"operation" binding:"required"`
-		Speed int32 `form:"speed"`
+		Op       int32  `form:"operation" binding:"required"`
+		Speed    int32  `form:"speed"`
+		Compress string `form:"compress"`
 	}
 	if err := c.BindQuery(&arg); err != nil {
 		errors(c, RequestErr, err.Error())
 		return
 	}
-	msg, err := ioutil.ReadAll(c.Request.Body)
+	msg, codec, err := readMsg(c, arg.Compress)
 	if err != nil {
 		errors(c, RequestErr, err.Error())
 		return
 	}
 	//todo token校验
-	if err = s.logic.PushAll(c, arg.Op, arg.Speed, msg); err != nil {
+	if err = s.logic.PushAll(c.Request.Context(), arg.Op, arg.Speed, msg, codec); err != nil {
 		errors(c, ServerErr, err.Error())
 		return
 	}
@@ -9,6 +9,8 @@ const (
 	OK = 0
 	// RequestErr request error
 	RequestErr = -400
+	// AuthErr the admin token is missing or wrong
+	AuthErr = -401
 	// ServerErr server error
 	ServerErr = -500
 
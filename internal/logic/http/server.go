@@ -7,6 +7,7 @@ import (
 )
 
 type Server struct {
+	c      *conf.HTTPServer
 	logic  *logic.Logic
 	engine *gin.Engine
 }
@@ -14,13 +15,14 @@ type Server struct {
 // New new a http server.
 func New(c *conf.HTTPServer, l *logic.Logic) *Server {
 	engine := gin.New()
-	engine.Use(loggerHandler, recoverHandler)
+	engine.Use(traceHandler, loggerHandler, recoverHandler)
 	go func() {
 		if err := engine.Run(c.Addr); err != nil {
 			panic(err)
 		}
 	}()
 	s := &Server{
+		c:      c,
 		engine: engine,
 		logic:  l,
 	}
@@ -34,11 +36,15 @@ func (s *Server) initRouter() {
 	group.POST("/push/mids", s.pushMids)
 	group.POST("/push/room", s.pushRoom)
 	group.POST("/push/all", s.pushAll)
+	//踢人接口和comet的admin接口一样需要token
+	kick := group.Group("/kick", adminHandler(s.c.AdminToken))
+	kick.POST("/keys", s.kickKeys)
+	kick.POST("/mids", s.kickMids)
 	group.GET("/online/top", s.onlineTop)
 	group.GET("/online/room", s.onlineRoom)
 	group.GET("/online/total", s.onlineTotal)
-	//group.GET("/nodes/weighted", s.nodesWeighted)
-	//group.GET("/nodes/instances", s.nodesInstances)
+	group.GET("/nodes/weighted", s.nodesWeighted)
+	group.GET("/nodes/instances", s.nodesInstances)
 
 	//todo 待实现
 	//userGroup := s.engine.Group("/user")
@@ -0,0 +1,82 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	log "github.com/golang/glog"
+)
+
+// ErrBanned the member is banned from connecting.
+var ErrBanned = errors.New("member banned")
+
+// KickKeys kick the keys off, the comets send OpKicked with the reason then close them.
+func (l *Logic) KickKeys(c context.Context, keys []string, reason string) (err error) {
+	if len(keys) == 0 {
+		return
+	}
+	//redis出错时返回给调用方, 不能让进程退出
+	servers, err := l.dao.ServersByKeys(c, keys)
+	if err != nil {
+		return
+	}
+	kickKeys := make(map[string][]string)
+	for i, key := range keys {
+		if server := servers[i]; server != "" && key != "" {
+			kickKeys[server] = append(kickKeys[server], key)
+		}
+	}
+	return l.kick(c, kickKeys, reason)
+}
+
+// KickMids kick all conns of the mids off, they can't connect again until the ban expires if ban > 0.
+func (l *Logic) KickMids(c context.Context, mids []int64, reason string, ban time.Duration) (err error) {
+	if ban > 0 {
+		for _, mid := range mids {
+			if err = l.dao.BanMid(c, mid, ban); err != nil {
+				return
+			}
+		}
+	}
+	keyServers, _, err := l.dao.KeysByMids(c, mids)
+	if err != nil {
+		return
+	}
+	kickKeys := make(map[string][]string)
+	for key, server := range keyServers {
+		if key != "" && server != "" {
+			kickKeys[server] = append(kickKeys[server], key)
+		}
+	}
+	return l.kick(c, kickKeys, reason)
+}
+
+func (l *Logic) kick(c context.Context, kickKeys map[string][]string, reason string) (err error) {
+	for server, keys := range kickKeys {
+		//先撤销会话, 即使踢人消息丢失comet也会在下次心跳时断开
+		for _, key := range keys {
+			if err = l.dao.RevokeKey(c, key); err != nil {
+				return
+			}
+		}
+		if err = l.dao.KickMsg(c, server, keys, reason); err != nil {
+			return
+		}
+		log.Infof("conns kicked server:%s keys:%v reason:%s", server, keys, reason)
+	}
+	return
+}
+
+// checkBanned reject the banned member, redis errors don't block connecting.
+func (l *Logic) checkBanned(c context.Context, mid int64) error {
+	banned, err := l.dao.Banned(c, mid)
+	if err != nil {
+		log.Errorf("l.dao.Banned(%d) error(%v)", mid, err)
+		return nil
+	}
+	if banned {
+		return ErrBanned
+	}
+	return nil
+}
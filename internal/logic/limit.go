@@ -0,0 +1,69 @@
+package logic
+
+import (
+	"context"
+	"errors"
+
+	log "github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// PolicyReject reject the new connection when over the limit.
+	PolicyReject = "reject"
+	// PolicyKick kick the oldest connections to make room for the new one.
+	PolicyKick = "kick"
+)
+
+var (
+	// ErrTooManyConns the member has too many connections.
+	ErrTooManyConns = errors.New("too many connections")
+
+	// limitStats 超过连接数限制的次数, mid_reject/mid_kick
+	limitStats = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "goim",
+		Subsystem: "logic",
+		Name:      "limited_total",
+		Help:      "Connections limited by the reason.",
+	}, []string{"reason"})
+)
+
+func init() {
+	prometheus.MustRegister(limitStats)
+}
+
+// addMapping add the mapping of the conn and enforce the max concurrent keys of
+// the mid cluster-wide in one redis script, key is not counted if it's reconnecting.
+func (l *Logic) addMapping(c context.Context, mid int64, key, server string) error {
+	var (
+		max  int
+		kick bool
+	)
+	if lc := l.c.Limit; lc != nil && lc.MaxMidConns > 0 {
+		max, kick = lc.MaxMidConns, lc.MidPolicy == PolicyKick
+	}
+	evicts, ok, err := l.dao.AddMappingLimit(c, mid, key, server, max, kick)
+	if err != nil {
+		log.Errorf("l.dao.AddMappingLimit(%d,%s,%s) error(%v)", mid, key, server, err)
+		return err
+	}
+	if !ok {
+		limitStats.WithLabelValues("mid_reject").Inc()
+		log.Warningf("conn rejected mid:%d key:%s max:%d", mid, key, max)
+		return ErrTooManyConns
+	}
+	if len(evicts) == 0 {
+		return nil
+	}
+	kickKeys := make(map[string][]string)
+	for k, s := range evicts {
+		kickKeys[s] = append(kickKeys[s], k)
+		limitStats.WithLabelValues("mid_kick").Inc()
+		log.Infof("conn kicked mid:%d key:%s by key:%s", mid, k, key)
+	}
+	//mapping已经删除, 通知comet立即断开最早的连接, 失败只记录日志不影响新连接
+	if err = l.kick(c, kickKeys, ErrTooManyConns.Error()); err != nil {
+		log.Errorf("l.kick(%d,%v) error(%v)", mid, kickKeys, err)
+	}
+	return nil
+}
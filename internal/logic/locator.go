@@ -0,0 +1,64 @@
+package logic
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+)
+
+// Locator locate the province of a client ip, used to prefer the comets in
+// the same region. Without a locator all the regions are equal.
+type Locator interface {
+	Province(ip string) (string, error)
+}
+
+// errNoProvince the client ip is not in any configured network.
+var errNoProvince = errors.New("no province of the ip")
+
+// cidrLocator locate the province by the client networks in the config.
+type cidrLocator struct {
+	nets []provinceNet // 掩码长的网段在前, 优先匹配
+}
+
+type provinceNet struct {
+	net      *net.IPNet
+	province string
+}
+
+// newLocator new the locator by the province -> networks config, nil if not configured.
+func newLocator(provinces map[string][]string) (Locator, error) {
+	if len(provinces) == 0 {
+		return nil, nil
+	}
+	l := new(cidrLocator)
+	for province, cidrs := range provinces {
+		for _, cidr := range cidrs {
+			_, n, err := net.ParseCIDR(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("province %s: %v", province, err)
+			}
+			l.nets = append(l.nets, provinceNet{net: n, province: province})
+		}
+	}
+	sort.SliceStable(l.nets, func(i, j int) bool {
+		oi, _ := l.nets[i].net.Mask.Size()
+		oj, _ := l.nets[j].net.Mask.Size()
+		return oi > oj
+	})
+	return l, nil
+}
+
+// Province get the province of the most specific network containing the ip.
+func (l *cidrLocator) Province(ip string) (string, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return "", fmt.Errorf("bad ip %q", ip)
+	}
+	for _, n := range l.nets {
+		if n.net.Contains(addr) {
+			return n.province, nil
+		}
+	}
+	return "", errNoProvince
+}
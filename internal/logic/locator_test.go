@@ -0,0 +1,36 @@
+package logic
+
+import (
+	"testing"
+)
+
+func TestLocator(t *testing.T) {
+	if l, err := newLocator(nil); l != nil || err != nil {
+		t.Fatalf("empty locator %v %v", l, err)
+	}
+	if _, err := newLocator(map[string][]string{"北京": {"10.0.0.0"}}); err == nil {
+		t.Fatal("bad network no error")
+	}
+	l, err := newLocator(map[string][]string{
+		"北京": {"10.0.0.0/8"},
+		"上海": {"10.2.0.0/16", "2001:db8::/32"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ip, want := range map[string]string{
+		"10.1.2.3":    "北京",
+		"10.2.3.4":    "上海",
+		"2001:db8::1": "上海",
+	} {
+		if got, err := l.Province(ip); err != nil || got != want {
+			t.Fatalf("Province(%s) %s %v want %s", ip, got, err, want)
+		}
+	}
+	if _, err := l.Province("192.168.0.1"); err != errNoProvince {
+		t.Fatalf("unknown ip %v", err)
+	}
+	if _, err := l.Province("bad"); err == nil {
+		t.Fatal("bad ip no error")
+	}
+}
@@ -2,10 +2,12 @@ package logic
 
 import (
 	"context"
+	log "github.com/golang/glog"
+	pb "go-im/api/connect"
 	"go-im/internal/logic/conf"
 	"go-im/internal/logic/dao"
-	model "go-im/internal/logic/dto"
-	"log"
+	"go-im/pkg/opset"
+	"sync"
 	"time"
 )
 
@@ -17,26 +19,49 @@ const (
 type Logic struct {
 	c *conf.Config
 	// online
+	onlineLock sync.RWMutex
 	totalIPs   int64
 	totalConns int64
 	roomCount  map[string]int32
-	regions    map[string]string // province -> region
-	dao        *dao.Dao
-	HostName   string
+	olServers  map[string]struct{} // 上次汇总的comet, 只在loadOnline中使用
+	regions    map[string]string   // province -> region
+	// comet nodes
+	nodes        map[string]*pb.Instance
+	nodesLock    sync.RWMutex
+	loadBalancer *LoadBalancer
+	locator      Locator
+	dao          *dao.Dao
+	offline      dao.Offline
+	auth         Authenticator
+	allowOps     opset.Set // 客户端可以订阅的op, nil不限制
 }
 
 func New(c *conf.Config) *Logic {
 	s := new(Logic)
 	s.c = c
-	s.HostName = "tt"
 	s.regions = make(map[string]string)
 	s.initRegions()
-	//todo etcd get all node info
+	locator, err := newLocator(c.Node.Provinces)
+	if err != nil {
+		panic(err)
+	}
+	s.locator = locator
+	s.nodes = make(map[string]*pb.Instance)
+	s.loadBalancer = NewLoadBalancer()
+	if err := s.watchNodes(); err != nil {
+		panic(err)
+	}
 
 	s.dao = dao.New(c)
-	s.initRegions()
+	s.initOffline()
+	auth, err := newAuthenticator(c.Auth, s.dao)
+	if err != nil {
+		panic(err)
+	}
+	s.auth = auth
+	s.allowOps = newAllowOps(c.Accept)
 
-	_ = s.loadOnline()
+	s.loadOnline()
 	go s.onlineproc()
 	return s
 }
@@ -48,33 +73,74 @@ func (l *Logic) initRegions() {
 		}
 	}
 }
+func (l *Logic) initOffline() {
+	if l.c.Offline == nil || !l.c.Offline.Open {
+		return
+	}
+	switch l.c.Offline.Driver {
+	case "memory":
+		l.offline = dao.NewMemoryOffline(l.c.Offline)
+	default:
+		l.offline = l.dao
+	}
+}
+
 func (l *Logic) onlineproc() {
 	for {
 		time.Sleep(_onlineTick)
-		if err := l.loadOnline(); err != nil {
-			log.Fatalf("onlineproc error(%v)", err)
-		}
+		l.loadOnline()
 	}
 }
 
-func (l *Logic) loadOnline() (err error) {
+// loadOnline merge the online of all the registered comets, the failed ones
+// are skipped. The stale online of the silent or deregistered comets is deleted.
+func (l *Logic) loadOnline() {
 	var (
-		roomCount = make(map[string]int32)
+		roomCount  = make(map[string]int32)
+		servers    = make(map[string]struct{})
+		totalIPs   int64
+		totalConns int64
 	)
-	//for _, server := range s.nodes {
-	var online *model.Online
-	online, err = l.dao.ServerOnline(context.Background(), l.HostName)
-	if err != nil {
-		return
-	}
-	if time.Since(time.Unix(online.Updated, 0)) > _onlineDeadline {
-		_ = l.dao.DelServerOnline(context.Background(), l.HostName)
-		return
+	for _, ins := range l.NodesInstances(context.Background()) {
+		servers[ins.Hostname] = struct{}{}
+		online, err := l.dao.ServerOnline(context.Background(), ins.Hostname)
+		if err != nil {
+			//跳过出错的comet, 其余的照常汇总
+			log.Errorf("l.dao.ServerOnline(%s) error(%v)", ins.Hostname, err)
+			continue
+		}
+		if online.Updated == 0 {
+			//刚注册还没有上报
+			continue
+		}
+		if time.Since(time.Unix(online.Updated, 0)) > _onlineDeadline {
+			_ = l.dao.DelServerOnline(context.Background(), ins.Hostname)
+			continue
+		}
+		for roomID, count := range online.RoomCount {
+			roomCount[roomID] += count
+		}
+		//同一个ip连接多个comet会重复计算
+		totalIPs += int64(online.IPCount)
+		totalConns += int64(online.ConnCount)
 	}
-	for roomID, count := range online.RoomCount {
-		roomCount[roomID] += count
+	//已经撤销注册的comet不会再被读取, 删除它的在线数据
+	for server := range l.olServers {
+		if _, ok := servers[server]; !ok {
+			_ = l.dao.DelServerOnline(context.Background(), server)
+		}
 	}
-	//}
+	l.olServers = servers
+	l.onlineLock.Lock()
 	l.roomCount = roomCount
-	return
+	l.totalIPs = totalIPs
+	l.totalConns = totalConns
+	l.onlineLock.Unlock()
+}
+
+// RoomCount get the online of all rooms in the cluster.
+func (l *Logic) RoomCount() map[string]int32 {
+	l.onlineLock.RLock()
+	defer l.onlineLock.RUnlock()
+	return l.roomCount
 }
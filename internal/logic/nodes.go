@@ -0,0 +1,88 @@
+package logic
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	log "github.com/golang/glog"
+	pb "go-im/api/connect"
+	"go-im/api/logic"
+	model "go-im/internal/logic/dto"
+	"go-im/pkg/etcd"
+)
+
+// watchNodes watch the comets registered in etcd.
+func (l *Logic) watchNodes() error {
+	dis, err := etcd.NewDiscovery([]string{l.c.Discovery.Host})
+	if err != nil {
+		return err
+	}
+	dis.OnChange(func(key, val string, deleted bool) {
+		server := strings.TrimPrefix(key, pb.DiscoveryPrefix)
+		l.nodesLock.Lock()
+		if deleted {
+			delete(l.nodes, server)
+		} else if ins, err := pb.ParseInstance(val); err == nil {
+			l.nodes[server] = ins
+		} else {
+			log.Errorf("pb.ParseInstance(%s) server:%s error(%v)", val, server, err)
+		}
+		l.loadBalancer.Update(l.nodes)
+		l.nodesLock.Unlock()
+	})
+	return dis.WatchSrv(pb.DiscoveryPrefix)
+}
+
+// NodesInstances get the registered comets.
+func (l *Logic) NodesInstances(c context.Context) (res []*pb.Instance) {
+	l.nodesLock.RLock()
+	defer l.nodesLock.RUnlock()
+	res = make([]*pb.Instance, 0, len(l.nodes))
+	for _, ins := range l.nodes {
+		res = append(res, ins)
+	}
+	return
+}
+
+// NodesWeighted get the comets for a client, ordered by weight and load.
+func (l *Logic) NodesWeighted(c context.Context, platform, clientIP string) *logic.NodesReply {
+	reply := &logic.NodesReply{
+		Domain:       l.c.Node.DefaultDomain,
+		TcpPort:      int32(l.c.Node.TCPPort),
+		WsPort:       int32(l.c.Node.WSPort),
+		WssPort:      int32(l.c.Node.WSSPort),
+		Heartbeat:    int32(l.c.Node.Heartbeat / time.Second),
+		HeartbeatMax: int32(l.c.Node.HeartbeatMax),
+		Backoff: &logic.Backoff{
+			MaxDelay:  l.c.Backoff.MaxDelay,
+			BaseDelay: l.c.Backoff.BaseDelay,
+			Factor:    l.c.Backoff.Factor,
+			Jitter:    l.c.Backoff.Jitter,
+		},
+	}
+	domains, addrs := l.nodeAddrs(clientIP)
+	//浏览器需要域名才能使用wss
+	if platform == model.PlatformWeb {
+		reply.Nodes = domains
+	} else {
+		reply.Nodes = addrs
+	}
+	if len(reply.Nodes) == 0 {
+		reply.Nodes = []string{l.c.Node.DefaultDomain}
+	}
+	return reply
+}
+
+func (l *Logic) nodeAddrs(clientIP string) (domains, addrs []string) {
+	var province, region string
+	if l.locator != nil && clientIP != "" {
+		var err error
+		if province, err = l.locator.Province(clientIP); err == nil {
+			region = l.regions[province]
+		}
+	}
+	domains, addrs = l.loadBalancer.NodeAddrs(region, l.c.Node.HostDomain, l.c.Node.RegionWeight)
+	log.V(1).Infof("nodeAddrs clientIP:%s region:%s province:%s domains:%v addrs:%v", clientIP, region, province, domains, addrs)
+	return
+}
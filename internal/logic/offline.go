@@ -0,0 +1,58 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	log "github.com/golang/glog"
+	"go-im/api/protocol"
+	"go-im/pkg/proto"
+)
+
+// pushOffline save the message into mailbox of members who have no live session.
+func (l *Logic) pushOffline(c context.Context, op int32, mids []int64, msg []byte) (err error) {
+	if l.offline == nil {
+		return
+	}
+	for _, mid := range mids {
+		if err = l.offline.AddOffline(c, mid, op, msg); err != nil {
+			log.Errorf("l.offline.AddOffline(%d,%d) error(%v)", mid, op, err)
+			return
+		}
+	}
+	return
+}
+
+// drainOffline replay the member's offline messages to the new session.
+// Messages are kept until the client acks them, so a replay lost on the way
+// will be sent again on the next connect.
+func (l *Logic) drainOffline(c context.Context, mid int64, key, server string) {
+	msgs, err := l.offline.Offlines(c, mid)
+	if err != nil {
+		log.Errorf("l.offline.Offlines(%d) error(%v)", mid, err)
+		return
+	}
+	if len(msgs) == 0 {
+		return
+	}
+	body, err := json.Marshal(msgs)
+	if err != nil {
+		return
+	}
+	if err = l.dao.PushMsg(c, protocol.OpOfflineMsg, server, []string{key}, body, int32(proto.CodecNone)); err != nil {
+		log.Errorf("l.dao.PushMsg(%d,%s,%s) offline error(%v)", mid, key, server, err)
+	}
+}
+
+// AckOffline the client confirm receipt of offline messages, body is the last received id.
+func (l *Logic) AckOffline(c context.Context, mid int64, body []byte) (err error) {
+	if l.offline == nil {
+		return
+	}
+	id, err := strconv.ParseInt(string(body), 10, 64)
+	if err != nil {
+		return
+	}
+	return l.offline.AckOffline(c, mid, id)
+}
@@ -13,7 +13,7 @@ var (
 
 // OnlineTop get the top online.
 func (l *Logic) OnlineTop(c context.Context, typ string, n int) (tops []*model.Top, err error) {
-	for key, cnt := range l.roomCount {
+	for key, cnt := range l.RoomCount() {
 		if strings.HasPrefix(key, typ) {
 			_, roomID, err := model.DecodeRoomKey(key)
 			if err != nil {
@@ -41,13 +41,16 @@ func (l *Logic) OnlineTop(c context.Context, typ string, n int) (tops []*model.T
 // OnlineRoom get rooms online.
 func (l *Logic) OnlineRoom(c context.Context, typ string, rooms []string) (res map[string]int32, err error) {
 	res = make(map[string]int32, len(rooms))
+	roomCount := l.RoomCount()
 	for _, room := range rooms {
-		res[room] = l.roomCount[model.EncodeRoomKey(typ, room)]
+		res[room] = roomCount[model.EncodeRoomKey(typ, room)]
 	}
 	return
 }
 
 // OnlineTotal get all online.
 func (l *Logic) OnlineTotal(c context.Context) (int64, int64) {
+	l.onlineLock.RLock()
+	defer l.onlineLock.RUnlock()
 	return l.totalIPs, l.totalConns
 }
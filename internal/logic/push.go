@@ -4,10 +4,11 @@ import (
 	"context"
 	log "github.com/golang/glog"
 	model "go-im/internal/logic/dto"
+	"go-im/pkg/proto"
 )
 
-// PushKeys push a message by keys.
-func (l *Logic) PushKeys(c context.Context, op int32, keys []string, msg []byte) (err error) {
+// PushKeys push a message by keys, msg is pre-compressed if codec is not CodecNone.
+func (l *Logic) PushKeys(c context.Context, op int32, keys []string, msg []byte, codec proto.Codec) (err error) {
 	servers, err := l.dao.ServersByKeys(c, keys)
 	if err != nil {
 		return
@@ -20,19 +21,26 @@ func (l *Logic) PushKeys(c context.Context, op int32, keys []string, msg []byte)
 		}
 	}
 	for server := range pushKeys {
-		if err = l.dao.PushMsg(c, op, server, pushKeys[server], msg); err != nil {
+		if err = l.dao.PushMsg(c, op, server, pushKeys[server], msg, int32(codec)); err != nil {
 			return
 		}
 	}
 	return
 }
 
-// PushMids push a message by mid.
-func (l *Logic) PushMids(c context.Context, op int32, mids []int64, msg []byte) (err error) {
-	keyServers, _, err := l.dao.KeysByMids(c, mids)
+// PushMids push a message by mid, msg is pre-compressed if codec is not CodecNone.
+func (l *Logic) PushMids(c context.Context, op int32, mids []int64, msg []byte, codec proto.Codec) (err error) {
+	keyServers, olMids, err := l.dao.KeysByMids(c, mids)
 	if err != nil {
 		return
 	}
+	//不在线的用户存入离线信箱, 登录后重新投递; 离线消息合并下发, 按原文保存
+	//离线信箱失败不影响在线用户的推送
+	if offMids := offlineMids(mids, olMids); len(offMids) > 0 {
+		if oerr := l.storeOffline(c, op, offMids, msg, codec); oerr != nil {
+			log.Errorf("l.storeOffline(%v) op:%d error(%v)", offMids, op, oerr)
+		}
+	}
 	keys := make(map[string][]string)
 	for key, server := range keyServers {
 		if key == "" || server == "" {
@@ -42,19 +50,45 @@ func (l *Logic) PushMids(c context.Context, op int32, mids []int64, msg []byte)
 		keys[server] = append(keys[server], key)
 	}
 	for server, keys := range keys {
-		if err = l.dao.PushMsg(c, op, server, keys, msg); err != nil {
+		if err = l.dao.PushMsg(c, op, server, keys, msg, int32(codec)); err != nil {
 			return
 		}
 	}
 	return
 }
 
+// storeOffline save the pre-compressed message to the offline mailboxes in plain.
+func (l *Logic) storeOffline(c context.Context, op int32, mids []int64, msg []byte, codec proto.Codec) error {
+	if l.offline == nil {
+		return nil
+	}
+	plain, err := proto.Decompress(codec, msg, proto.MaxDecompressSize)
+	if err != nil {
+		return err
+	}
+	return l.pushOffline(c, op, mids, plain)
+}
+
+// offlineMids get mids which have no live key->server mapping.
+func offlineMids(mids, olMids []int64) (res []int64) {
+	online := make(map[int64]struct{}, len(olMids))
+	for _, mid := range olMids {
+		online[mid] = struct{}{}
+	}
+	for _, mid := range mids {
+		if _, ok := online[mid]; !ok {
+			res = append(res, mid)
+		}
+	}
+	return
+}
+
 // PushRoom push a message by room.
-func (l *Logic) PushRoom(c context.Context, op int32, typ, room string, msg []byte) (err error) {
-	return l.dao.BroadcastRoomMsg(c, op, model.EncodeRoomKey(typ, room), msg)
+func (l *Logic) PushRoom(c context.Context, op int32, typ, room string, msg []byte, codec proto.Codec) (err error) {
+	return l.dao.BroadcastRoomMsg(c, op, model.EncodeRoomKey(typ, room), msg, int32(codec))
 }
 
 // PushAll push a message to all.
-func (l *Logic) PushAll(c context.Context, op, speed int32, msg []byte) (err error) {
-	return l.dao.BroadcastMsg(c, op, speed, msg)
+func (l *Logic) PushAll(c context.Context, op, speed int32, msg []byte, codec proto.Codec) (err error) {
+	return l.dao.BroadcastMsg(c, op, speed, msg, int32(codec))
 }
@@ -0,0 +1,106 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	log "github.com/golang/glog"
+	"go-im/api/protocol"
+	model "go-im/internal/logic/dto"
+	"go-im/pkg/proto"
+)
+
+const (
+	// 业务消息的操作码从1000开始, 之前的为协议保留
+	_minMsgOp = int32(1000)
+)
+
+var (
+	// ErrInvalidMsg upstream message is malformed.
+	ErrInvalidMsg = errors.New("invalid message")
+	// ErrNotAllowed sender is not allowed to post to the target.
+	ErrNotAllowed = errors.New("not allowed to post")
+)
+
+// Receive receive a message from client.
+// rooms are the rooms the sender's channel is in.
+func (l *Logic) Receive(c context.Context, mid int64, rooms []string, p *protocol.Proto) (err error) {
+	switch p.Op {
+	case protocol.OpOfflineAck:
+		err = l.AckOffline(c, mid, p.Body)
+	case protocol.OpSendMsg:
+		err = l.receiveMsg(c, mid, rooms, p.Body)
+	default:
+		log.Warningf("receive mid:%d unknown op:%d", mid, p.Op)
+	}
+	return
+}
+
+// receiveMsg route an upstream message to its target.
+func (l *Logic) receiveMsg(c context.Context, mid int64, rooms []string, body []byte) (err error) {
+	env := new(model.Envelope)
+	if err = json.Unmarshal(body, env); err != nil || env.Op < _minMsgOp {
+		return ErrInvalidMsg
+	}
+	msg, err := json.Marshal(&model.Message{
+		From:  mid,
+		Type:  env.Type,
+		ToMid: env.ToMid,
+		Room:  env.Room,
+		Group: env.Group,
+		Msg:   env.Msg,
+	})
+	if err != nil {
+		return
+	}
+	switch env.Type {
+	case model.MsgTypePeer:
+		if env.ToMid == 0 {
+			return ErrInvalidMsg
+		}
+		//和房间、群组一样, 只能发给业务方允许的联系人
+		var ok bool
+		if ok, err = l.dao.IsContact(c, mid, env.ToMid); err != nil {
+			return
+		}
+		if !ok {
+			return ErrNotAllowed
+		}
+		return l.PushMids(c, env.Op, []int64{env.ToMid}, msg, proto.CodecNone)
+	case model.MsgTypeRoom:
+		if !inRooms(env.Room, rooms) {
+			return ErrNotAllowed
+		}
+		return l.dao.BroadcastRoomMsg(c, env.Op, env.Room, msg, int32(proto.CodecNone))
+	case model.MsgTypeGroup:
+		var (
+			ok   bool
+			mids []int64
+		)
+		if ok, err = l.dao.IsGroupMember(c, env.Group, mid); err != nil {
+			return
+		}
+		if !ok {
+			return ErrNotAllowed
+		}
+		if mids, err = l.dao.GroupMembers(c, env.Group); err != nil {
+			return
+		}
+		return l.PushMids(c, env.Op, mids, msg, proto.CodecNone)
+	default:
+		return ErrInvalidMsg
+	}
+}
+
+func inRooms(room string, rooms []string) bool {
+	if room == "" {
+		return false
+	}
+	for _, r := range rooms {
+		if r == room {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+// Package certs load the tls certificates which can be reloaded without restart.
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"sync/atomic"
+)
+
+var ErrNoCerts = errors.New("no certificates found")
+
+// Reloader hold the certificate of a key pair, the new connections use the
+// reloaded certificate and the established ones are not affected.
+type Reloader struct {
+	certFile string
+	keyFile  string
+	cert     atomic.Value // *tls.Certificate
+}
+
+// NewReloader load the key pair.
+func NewReloader(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload load the key pair again, the old certificate is kept if failed.
+func (r *Reloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	r.cert.Store(&cert)
+	return nil
+}
+
+// Certificate get the current certificate.
+func (r *Reloader) Certificate() *tls.Certificate {
+	return r.cert.Load().(*tls.Certificate)
+}
+
+// GetCertificate is used as tls.Config.GetCertificate of the server.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.Certificate(), nil
+}
+
+// GetClientCertificate is used as tls.Config.GetClientCertificate of the client.
+func (r *Reloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.Certificate(), nil
+}
+
+// LoadCertPool load the pem encoded CA certificates.
+func LoadCertPool(file string) (*x509.CertPool, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, ErrNoCerts
+	}
+	return pool, nil
+}
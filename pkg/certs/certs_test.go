@@ -0,0 +1,97 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCert write a self-signed key pair of the common name.
+func writeCert(t *testing.T, certFile, keyFile, cn string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func commonName(t *testing.T, r *Reloader) string {
+	cert, err := r.GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeCert(t, certFile, keyFile, "old")
+	r, err := NewReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cn := commonName(t, r); cn != "old" {
+		t.Fatalf("cn %s", cn)
+	}
+
+	writeCert(t, certFile, keyFile, "new")
+	if err = r.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if cn := commonName(t, r); cn != "new" {
+		t.Fatalf("cn %s after reload", cn)
+	}
+
+	//加载失败保留旧证书
+	if err = ioutil.WriteFile(keyFile, []byte("broken"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Reload(); err == nil {
+		t.Fatal("reload broken key pair")
+	}
+	if cn := commonName(t, r); cn != "new" {
+		t.Fatalf("cn %s after failed reload", cn)
+	}
+
+	if pool, err := LoadCertPool(certFile); err != nil || pool == nil {
+		t.Fatalf("load cert pool %v", err)
+	}
+	if _, err = LoadCertPool(keyFile); err != ErrNoCerts {
+		t.Fatalf("load cert pool err %v", err)
+	}
+}
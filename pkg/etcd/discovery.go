@@ -9,9 +9,10 @@ import (
 )
 
 type Discovery struct {
-	client  *clientv3.Client
-	srvList map[string]string
-	lock    sync.Mutex
+	client   *clientv3.Client
+	srvList  map[string]string
+	lock     sync.Mutex
+	onChange func(key, val string, deleted bool)
 }
 
 // NewDiscovery 发现服务
@@ -30,6 +31,11 @@ func NewDiscovery(endpoints []string) (*Discovery, error) {
 	}, nil
 }
 
+// OnChange 服务变更时的回调, 需要在WatchSrv之前设置
+func (s *Discovery) OnChange(fn func(key, val string, deleted bool)) {
+	s.onChange = fn
+}
+
 // WatchSrv  初始化服务列表和监视
 func (s *Discovery) WatchSrv(prefix string) error {
 	//根据前缀获取现有的key
@@ -47,6 +53,23 @@ func (s *Discovery) WatchSrv(prefix string) error {
 	return nil
 }
 
+// List 获取前缀下当前的服务, 不监视变更
+func (s *Discovery) List(prefix string) (map[string]string, error) {
+	return listPrefix(s.client, prefix)
+}
+
+func listPrefix(client *clientv3.Client, prefix string) (map[string]string, error) {
+	resp, err := client.Get(context.TODO(), prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+	srvs := make(map[string]string, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		srvs[string(kv.Key)] = string(kv.Value)
+	}
+	return srvs, nil
+}
+
 //watcher 监听前缀
 func (s *Discovery) watcher(prefix string) {
 	rch := s.client.Watch(context.Background(), prefix, clientv3.WithPrefix())
@@ -65,15 +88,21 @@ func (s *Discovery) watcher(prefix string) {
 // SetSrvList  新增服务地址
 func (s *Discovery) SetSrvList(key, val string) {
 	s.lock.Lock()
-	defer s.lock.Unlock()
 	s.srvList[key] = val
+	s.lock.Unlock()
+	if s.onChange != nil {
+		s.onChange(key, val, false)
+	}
 }
 
 // DelSrvList 删除服务地址
 func (s *Discovery) DelSrvList(key string) {
 	s.lock.Lock()
-	defer s.lock.Unlock()
 	delete(s.srvList, key)
+	s.lock.Unlock()
+	if s.onChange != nil {
+		s.onChange(key, "", true)
+	}
 }
 
 // GetSrv 获取服务地址
@@ -38,7 +38,7 @@ func NewRegister(endpoint []string, key, value string, lease int64) (*Register,
 		return register, err
 	}
 
-	return nil, nil
+	return register, nil
 }
 
 //设置租约
@@ -70,18 +70,23 @@ func (r *Register) putKeyWithLease(lease int64) error {
 	r.leaseId = leaseGrantResponse.ID
 
 	go func() {
-		for {
-			select {
-			case keepResp := <-r.keepAliveChan: // 自动续租的应答
-				if keepResp == nil {
-					r.deRegister()
-				}
-			}
+		//续租失败时keepAliveChan会被关闭
+		for range r.keepAliveChan {
 		}
+		r.deRegister()
 	}()
 	return nil
 }
 
+// Update 更新注册的值, 沿用原来的租约
+func (r *Register) Update(value string) error {
+	if _, err := r.client.Put(context.TODO(), r.key, value, clientv3.WithLease(r.leaseId)); err != nil {
+		return err
+	}
+	r.value = value
+	return nil
+}
+
 func (r *Register) deRegister() error {
 	defer r.client.Close()
 	_, err := r.client.Revoke(context.TODO(), r.leaseId)
@@ -92,6 +97,17 @@ func (r *Register) deRegister() error {
 	return nil
 }
 
+// List 用注册的客户端获取前缀下当前的服务, 撤销注册后续租协程会关闭客户端, 需在Deregister前调用
+func (r *Register) List(prefix string) (map[string]string, error) {
+	return listPrefix(r.client, prefix)
+}
+
+// Deregister 立即撤销租约删除注册, 不用等租约过期
+func (r *Register) Deregister() error {
+	_, err := r.client.Revoke(context.TODO(), r.leaseId)
+	return err
+}
+
 func (r *Register) Close() {
 	if r.client != nil {
 		r.client.Close()
@@ -0,0 +1,26 @@
+// Package metrics expose the prometheus metrics of the binaries.
+package metrics
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Path the path of the metrics endpoint.
+const Path = "/metrics"
+
+// Serve listen on addr and serve the metrics endpoint in background.
+func Serve(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle(Path, promhttp.Handler())
+	go func() {
+		_ = http.Serve(ln, mux)
+	}()
+	return nil
+}
@@ -0,0 +1,171 @@
+// Package opset is a set of message ops described by specs like "1000,2000-2999,*".
+package opset
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// All match all the ops, written as "*".
+var All = Range{Min: 0, Max: math.MaxInt32}
+
+// Range is the ops from Min to Max inclusive.
+type Range struct {
+	Min, Max int32
+}
+
+// Set is a set of ops kept as sorted and disjoint ranges, the zero value is empty.
+type Set []Range
+
+// Parse parse the specs joined by comma, a spec is an op "1000", a range
+// "1000-1999" or "*" for all, the empty ones are skipped.
+func Parse(specs ...string) (s Set, err error) {
+	for _, spec := range specs {
+		for _, item := range strings.Split(spec, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			r, err := parseRange(item)
+			if err != nil {
+				return nil, err
+			}
+			s.Add(r)
+		}
+	}
+	return
+}
+
+func parseRange(item string) (r Range, err error) {
+	if item == "*" {
+		return All, nil
+	}
+	min, max := item, item
+	if i := strings.IndexByte(item, '-'); i > 0 {
+		min, max = item[:i], item[i+1:]
+	}
+	if r.Min, err = parseOp(min); err != nil {
+		return
+	}
+	if r.Max, err = parseOp(max); err != nil {
+		return
+	}
+	if r.Min > r.Max {
+		err = fmt.Errorf("opset: bad range %q", item)
+	}
+	return
+}
+
+func parseOp(s string) (int32, error) {
+	op, err := strconv.ParseInt(strings.TrimSpace(s), 10, 32)
+	if err != nil || op < 0 {
+		return 0, fmt.Errorf("opset: bad op %q", s)
+	}
+	return int32(op), nil
+}
+
+// Of get the set of the ops.
+func Of(ops ...int32) (s Set) {
+	for _, op := range ops {
+		s.Add(Range{Min: op, Max: op})
+	}
+	return
+}
+
+// Has reports whether the op is in the set.
+func (s Set) Has(op int32) bool {
+	i := sort.Search(len(s), func(i int) bool { return s[i].Max >= op })
+	return i < len(s) && s[i].Min <= op
+}
+
+// Add add the range, the overlapping and adjacent ranges are merged.
+func (s *Set) Add(r Range) {
+	old := *s
+	res := make(Set, 0, len(old)+1)
+	i := 0
+	for ; i < len(old) && int64(old[i].Max)+1 < int64(r.Min); i++ {
+		res = append(res, old[i])
+	}
+	for ; i < len(old) && int64(old[i].Min) <= int64(r.Max)+1; i++ {
+		if old[i].Min < r.Min {
+			r.Min = old[i].Min
+		}
+		if old[i].Max > r.Max {
+			r.Max = old[i].Max
+		}
+	}
+	res = append(res, r)
+	*s = append(res, old[i:]...)
+}
+
+// Del remove the range, the ranges partly covered are split.
+func (s *Set) Del(r Range) {
+	var res Set
+	for _, o := range *s {
+		if o.Max < r.Min || o.Min > r.Max {
+			res = append(res, o)
+			continue
+		}
+		if o.Min < r.Min {
+			res = append(res, Range{Min: o.Min, Max: r.Min - 1})
+		}
+		if o.Max > r.Max {
+			res = append(res, Range{Min: r.Max + 1, Max: o.Max})
+		}
+	}
+	*s = res
+}
+
+// Union add all the ranges of o.
+func (s *Set) Union(o Set) {
+	for _, r := range o {
+		s.Add(r)
+	}
+}
+
+// Sub remove all the ranges of o.
+func (s *Set) Sub(o Set) {
+	for _, r := range o {
+		s.Del(r)
+	}
+}
+
+// Intersect get the ops both in s and o.
+func (s Set) Intersect(o Set) (res Set) {
+	for i, j := 0, 0; i < len(s) && j < len(o); {
+		r := s[i]
+		if o[j].Min > r.Min {
+			r.Min = o[j].Min
+		}
+		if o[j].Max < r.Max {
+			r.Max = o[j].Max
+		}
+		if r.Min <= r.Max {
+			res = append(res, r)
+		}
+		if s[i].Max < o[j].Max {
+			i++
+		} else {
+			j++
+		}
+	}
+	return
+}
+
+// String format the set as specs like "1000,2000-2999".
+func (s Set) String() string {
+	items := make([]string, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r == All:
+			items = append(items, "*")
+		case r.Min == r.Max:
+			items = append(items, strconv.FormatInt(int64(r.Min), 10))
+		default:
+			items = append(items, fmt.Sprintf("%d-%d", r.Min, r.Max))
+		}
+	}
+	return strings.Join(items, ",")
+}
@@ -0,0 +1,54 @@
+package opset
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for spec, want := range map[string]string{
+		"":                         "",
+		"1000":                     "1000",
+		" 1001, 1000 ,":            "1000-1001",
+		"2000-2999,1000,2500-3000": "1000,2000-3000",
+		"*,1000":                   "*",
+	} {
+		s, err := Parse(spec)
+		if err != nil || s.String() != want {
+			t.Fatalf("Parse(%q) %q %v want %q", spec, s, err, want)
+		}
+	}
+	for _, spec := range []string{"a", "-1", "2000-1000", "1000-", "1-2-3", "4294967296"} {
+		if _, err := Parse(spec); err == nil {
+			t.Fatalf("Parse(%q) no error", spec)
+		}
+	}
+}
+
+func TestSet(t *testing.T) {
+	s, _ := Parse("1000-1999,3000")
+	if !s.Has(1000) || !s.Has(1999) || !s.Has(3000) || s.Has(999) || s.Has(2000) || s.Has(3001) {
+		t.Fatalf("Has %s", s)
+	}
+	s.Del(Range{Min: 1500, Max: 1500})
+	s.Sub(Of(3000, 1000))
+	if got := s.String(); got != "1001-1499,1501-1999" {
+		t.Fatalf("Del %s", got)
+	}
+	s.Union(Of(1500, 1000))
+	if got := s.String(); got != "1000-1999" {
+		t.Fatalf("Union %s", got)
+	}
+
+	allow, _ := Parse("500-1200,1900-2100,5000")
+	if got := s.Intersect(allow).String(); got != "1000-1200,1900-1999" {
+		t.Fatalf("Intersect %s", got)
+	}
+	all := Set{All}
+	if got := all.Intersect(s).String(); got != "1000-1999" {
+		t.Fatalf("Intersect all %s", got)
+	}
+	all.Del(Range{Min: 0, Max: 0})
+	if all.Has(0) || !all.Has(1<<31-1) {
+		t.Fatalf("Del from all %s", all)
+	}
+}
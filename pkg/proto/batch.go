@@ -0,0 +1,45 @@
+package proto
+
+import (
+	"go-im/api/protocol"
+)
+
+// AppendPackage append the package of the proto to a batch body.
+// The body of an OpRaw batch is the packages of the merged protos one by one.
+func AppendPackage(batch []byte, p *protocol.Proto) []byte {
+	n := len(batch)
+	size := RawHeaderSize + len(p.Body)
+	if cap(batch)-n < size {
+		nb := make([]byte, n, 2*cap(batch)+size)
+		copy(nb, batch)
+		batch = nb
+	}
+	batch = batch[:n+size]
+	EncodeTo(batch[n:], p)
+	return batch
+}
+
+// SplitBatch decode the protos merged in an OpRaw batch body, the bodies
+// reference the batch.
+func SplitBatch(batch []byte) (ps []*protocol.Proto, err error) {
+	for len(batch) > 0 {
+		if len(batch) < RawHeaderSize {
+			return nil, ErrPackageLen
+		}
+		p := new(protocol.Proto)
+		var bodyLen int
+		if bodyLen, err = decodeHeader(batch, p, len(batch)); err != nil {
+			return nil, err
+		}
+		size := RawHeaderSize + bodyLen
+		if len(batch) < size {
+			return nil, ErrPackageLen
+		}
+		if bodyLen > 0 {
+			p.Body = batch[RawHeaderSize:size]
+		}
+		ps = append(ps, p)
+		batch = batch[size:]
+	}
+	return
+}
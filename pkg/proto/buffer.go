@@ -0,0 +1,64 @@
+package proto
+
+import (
+	"math/bits"
+	"sync"
+)
+
+const (
+	_minBufferBits = 4  // 16B
+	_maxBufferBits = 16 // 64KB
+)
+
+// Buffer is a byte slice from the pool, put it back by PutBuffer when it's
+// no longer referenced.
+type Buffer struct {
+	b     []byte
+	class int
+}
+
+// Bytes get the bytes of the buffer.
+func (b *Buffer) Bytes() []byte {
+	return b.b
+}
+
+// 按2的幂次分级, 避免小包占用大缓冲
+var bufferPools [_maxBufferBits - _minBufferBits + 1]sync.Pool
+
+func init() {
+	for i := range bufferPools {
+		size := 1 << (i + _minBufferBits)
+		class := i
+		bufferPools[i].New = func() interface{} {
+			return &Buffer{b: make([]byte, size), class: class}
+		}
+	}
+}
+
+func bufferClass(size int) int {
+	if size <= 1<<_minBufferBits {
+		return 0
+	}
+	return bits.Len(uint(size-1)) - _minBufferBits
+}
+
+// GetBuffer get a buffer whose length is size, buffers larger than the max
+// class are not pooled.
+func GetBuffer(size int) *Buffer {
+	class := bufferClass(size)
+	if class >= len(bufferPools) {
+		return &Buffer{b: make([]byte, size), class: -1}
+	}
+	buf := bufferPools[class].Get().(*Buffer)
+	buf.b = buf.b[:size]
+	return buf
+}
+
+// PutBuffer put the buffer back to the pool.
+func PutBuffer(buf *Buffer) {
+	if buf == nil || buf.class < 0 {
+		return
+	}
+	buf.b = buf.b[:cap(buf.b)]
+	bufferPools[buf.class].Put(buf)
+}
@@ -0,0 +1,158 @@
+package proto
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codec is the compression of the body, it's carried by the high 4 bits of the
+// 16 bits version in the header, so the version itself is at most VerMask.
+type Codec uint8
+
+const (
+	CodecNone Codec = iota
+	CodecGzip
+	CodecZstd
+	CodecSnappy
+	// NumCodecs the count of the codecs, a codec >= NumCodecs is unknown.
+	NumCodecs
+)
+
+const (
+	codecShift = 12
+	// VerMask the bits of the version without the codec.
+	VerMask = int32(1<<codecShift - 1)
+	// MaxDecompressSize 预压缩消息解压后的上限, 同grpc的最大消息
+	MaxDecompressSize = 1 << 24
+)
+
+var (
+	ErrCodec = errors.New("unknown compress codec")
+
+	codecNames = [NumCodecs]string{"", "gzip", "zstd", "snappy"}
+
+	gzipWriters = sync.Pool{
+		New: func() interface{} {
+			return gzip.NewWriter(nil)
+		},
+	}
+	// zstd的EncodeAll和DecodeAll可以并发调用
+	zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+	zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0), zstd.WithDecoderMaxMemory(MaxDecompressSize))
+)
+
+// ParseCodec parse the codec name, "" and "none" means no compression.
+func ParseCodec(name string) (Codec, error) {
+	if name == "none" {
+		return CodecNone, nil
+	}
+	for c, n := range codecNames {
+		if n == name {
+			return Codec(c), nil
+		}
+	}
+	return CodecNone, ErrCodec
+}
+
+// Valid reports whether the codec is known.
+func (c Codec) Valid() bool {
+	return c < NumCodecs
+}
+
+func (c Codec) String() string {
+	if c >= NumCodecs {
+		return "unknown"
+	}
+	if c == CodecNone {
+		return "none"
+	}
+	return codecNames[c]
+}
+
+// CodecOf get the codec of the body from the version in the header.
+func CodecOf(ver int32) Codec {
+	return Codec(uint16(ver) >> codecShift)
+}
+
+// BaseVer get the protocol version without the codec.
+func BaseVer(ver int32) int32 {
+	return ver & VerMask
+}
+
+// WithCodec set the codec of the body into the version.
+func WithCodec(ver int32, c Codec) int32 {
+	return BaseVer(ver) | int32(c)<<codecShift
+}
+
+// Compress compress the body, the result is newly allocated.
+func Compress(c Codec, body []byte) ([]byte, error) {
+	switch c {
+	case CodecNone:
+		return body, nil
+	case CodecGzip:
+		var buf bytes.Buffer
+		w := gzipWriters.Get().(*gzip.Writer)
+		defer gzipWriters.Put(w)
+		w.Reset(&buf)
+		if _, err := w.Write(body); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case CodecZstd:
+		return zstdEncoder.EncodeAll(body, nil), nil
+	case CodecSnappy:
+		return snappy.Encode(nil, body), nil
+	}
+	return nil, ErrCodec
+}
+
+// Decompress decompress the body, returns ErrBodyLen if the decompressed body
+// exceeds maxBody, which is checked before all is decompressed.
+func Decompress(c Codec, body []byte, maxBody int) ([]byte, error) {
+	switch c {
+	case CodecNone:
+		if len(body) > maxBody {
+			return nil, ErrBodyLen
+		}
+		return body, nil
+	case CodecGzip:
+		r, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		//多读一个字节判断是否超过限制
+		b, err := io.ReadAll(io.LimitReader(r, int64(maxBody)+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(b) > maxBody {
+			return nil, ErrBodyLen
+		}
+		return b, nil
+	case CodecZstd:
+		b, err := zstdDecoder.DecodeAll(body, nil)
+		if err == zstd.ErrDecoderSizeExceeded || err == zstd.ErrWindowSizeExceeded || len(b) > maxBody {
+			return nil, ErrBodyLen
+		}
+		return b, err
+	case CodecSnappy:
+		n, err := snappy.DecodedLen(body)
+		if err != nil {
+			return nil, err
+		}
+		if n > maxBody {
+			return nil, ErrBodyLen
+		}
+		return snappy.Decode(nil, body)
+	}
+	return nil, ErrCodec
+}
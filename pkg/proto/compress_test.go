@@ -0,0 +1,61 @@
+package proto
+
+import (
+	"bytes"
+	"testing"
+
+	"go-im/api/protocol"
+)
+
+func TestCompress(t *testing.T) {
+	body := bytes.Repeat([]byte(`{"msg":"hello"}`), 100)
+	for c := CodecGzip; c < NumCodecs; c++ {
+		name := c.String()
+		if got, err := ParseCodec(name); err != nil || got != c {
+			t.Fatalf("ParseCodec(%s) %v %v", name, got, err)
+		}
+		zb, err := Compress(c, body)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		if len(zb) >= len(body) {
+			t.Fatalf("%s not compressed %d", name, len(zb))
+		}
+		got, err := Decompress(c, zb, len(body))
+		if err != nil || !bytes.Equal(got, body) {
+			t.Fatalf("%s decompress %v", name, err)
+		}
+		//解压后超过限制
+		if _, err = Decompress(c, zb, len(body)-1); err != ErrBodyLen {
+			t.Fatalf("%s limit err %v", name, err)
+		}
+		if _, err = Decompress(c, body, len(body)); err == nil {
+			t.Fatalf("%s decompress garbage", name)
+		}
+	}
+	if _, err := ParseCodec("lz4"); err != ErrCodec {
+		t.Fatal(err)
+	}
+	if !CodecSnappy.Valid() || NumCodecs.Valid() {
+		t.Fatal("codec valid")
+	}
+	if _, err := Decompress(NumCodecs, body, len(body)); err != ErrCodec {
+		t.Fatal(err)
+	}
+}
+
+func TestVerCodec(t *testing.T) {
+	ver := WithCodec(2, CodecZstd)
+	if CodecOf(ver) != CodecZstd || BaseVer(ver) != 2 {
+		t.Fatalf("ver %x", ver)
+	}
+	//编码后仍在16位的版本号内
+	p := Encode(&protocol.Proto{Ver: ver})
+	got := new(protocol.Proto)
+	if err := Decode(p, got); err != nil || got.Ver != ver {
+		t.Fatalf("decode ver %x %v", got.Ver, err)
+	}
+	if ver = WithCodec(ver, CodecNone); ver != 2 {
+		t.Fatalf("ver %x", ver)
+	}
+}
@@ -5,11 +5,13 @@ import (
 	"encoding/binary"
 	"errors"
 	"go-im/api/protocol"
-	"strconv"
+	"io"
+	"net"
+	"sync"
 )
 
 const (
-	// MaxBodySize 最大数据字节长度 4096
+	// MaxBodySize 默认的最大body字节长度 4096, 不同协议版本可以单独配置
 	MaxBodySize = int32(1 << 12)
 	//PacketLen 包长度，在数据流传输过程中，先写入整个包的长度，方便整个包的数据读取。
 	//HeaderLen 头长度，在处理数据时，会先解析头部，可以知道具体业务操作。
@@ -35,83 +37,188 @@ const (
 	HeartIndex = SequenceIndex + SequenceSize
 )
 
-func ReadTcp(p *protocol.Proto, reader *bufio.Reader) error {
-	var (
-		buf        []byte
-		err        error
-		packageLen uint32
-		headerLen  uint16
-		bodyLen    uint32
-	)
+var (
+	ErrPackageLen = errors.New("package length error")
+	ErrHeaderLen  = errors.New("header length error")
+	ErrBodyLen    = errors.New("body length exceeds the limit")
+)
 
-	if buf, err = reader.Peek(RawHeaderSize); err != nil {
+// Encode 按tcp的包格式编码, websocket的二进制模式复用同样的格式
+func Encode(p *protocol.Proto) []byte {
+	buf := make([]byte, RawHeaderSize+len(p.Body))
+	EncodeTo(buf, p)
+	return buf
+}
+
+// EncodeTo 编码到buf中, buf的长度必须是RawHeaderSize+len(p.Body)
+func EncodeTo(buf []byte, p *protocol.Proto) {
+	encodeHeader(buf, p, len(p.Body))
+	copy(buf[RawHeaderSize:], p.Body)
+}
+
+// Decode 解析一个完整的包, body引用buf的内存
+func Decode(buf []byte, p *protocol.Proto) error {
+	if len(buf) < RawHeaderSize {
+		return ErrPackageLen
+	}
+	//buf已经是完整的包, 长度由调用方限制
+	bodyLen, err := decodeHeader(buf, p, len(buf))
+	if err != nil {
 		return err
 	}
+	if len(buf) != RawHeaderSize+bodyLen {
+		return ErrPackageLen
+	}
+	if bodyLen > 0 {
+		p.Body = buf[RawHeaderSize:]
+	} else {
+		p.Body = nil
+	}
+	return nil
+}
+
+func encodeHeader(buf []byte, p *protocol.Proto, bodyLen int) {
+	binary.BigEndian.PutUint32(buf[PackIndex:HeaderIndex], uint32(RawHeaderSize+bodyLen))
+	binary.BigEndian.PutUint16(buf[HeaderIndex:VersionIndex], uint16(RawHeaderSize))
+	binary.BigEndian.PutUint16(buf[VersionIndex:OperateIndex], uint16(p.Ver))
+	binary.BigEndian.PutUint32(buf[OperateIndex:SequenceIndex], uint32(p.Op))
+	binary.BigEndian.PutUint32(buf[SequenceIndex:RawHeaderSize], uint32(p.Seq))
+}
 
-	packageLen = binary.BigEndian.Uint32(buf[PackIndex:HeaderIndex])
-	headerLen = binary.BigEndian.Uint16(buf[HeaderIndex:VersionIndex])
+// decodeHeader 解析头信息, 返回body的长度, body超过maxBody返回ErrBodyLen
+func decodeHeader(buf []byte, p *protocol.Proto, maxBody int) (bodyLen int, err error) {
+	packageLen := binary.BigEndian.Uint32(buf[PackIndex:HeaderIndex])
+	headerLen := binary.BigEndian.Uint16(buf[HeaderIndex:VersionIndex])
 	p.Ver = int32(binary.BigEndian.Uint16(buf[VersionIndex:OperateIndex]))
 	p.Op = int32(binary.BigEndian.Uint32(buf[OperateIndex:SequenceIndex]))
 	p.Seq = int32(binary.BigEndian.Uint32(buf[SequenceIndex:RawHeaderSize]))
-
-	if packageLen > uint32(MaxBodySize) {
-		return errors.New("package length error")
+	if packageLen < RawHeaderSize {
+		return 0, ErrPackageLen
 	}
 	if headerLen != RawHeaderSize {
-		return errors.New("header length error")
+		return 0, ErrHeaderLen
+	}
+	if bodyLen = int(packageLen) - RawHeaderSize; bodyLen > maxBody {
+		return 0, ErrBodyLen
+	}
+	return bodyLen, nil
+}
+
+// ReadTcp read a package, the body is allocated and owned by the proto.
+// The body is limited by MaxBodySize.
+func ReadTcp(p *protocol.Proto, reader *bufio.Reader) error {
+	bodyLen, err := readHeader(p, reader, int(MaxBodySize))
+	if err != nil {
+		return err
 	}
-	if bodyLen = packageLen - RawHeaderSize; bodyLen > 0 {
-		pack := make([]byte, int(RawHeaderSize+bodyLen))
-		_, err = reader.Read(pack)
-		if err != nil {
+	if bodyLen > 0 {
+		p.Body = make([]byte, bodyLen)
+		if _, err = io.ReadFull(reader, p.Body); err != nil {
 			return err
 		}
-		p.Body = pack[RawHeaderSize:]
 	} else {
 		p.Body = nil
 	}
+	return nil
+}
 
-	return err
+// ReadTcpBuf read a package, the body is read into a pooled buffer which must
+// be put back by PutBuffer once the proto is handled. buf is nil if the body is empty.
+// Returns ErrBodyLen if the body exceeds maxBody, the body is not read.
+func ReadTcpBuf(p *protocol.Proto, reader *bufio.Reader, maxBody int32) (buf *Buffer, err error) {
+	bodyLen, err := readHeader(p, reader, int(maxBody))
+	if err != nil {
+		return nil, err
+	}
+	if bodyLen == 0 {
+		p.Body = nil
+		return nil, nil
+	}
+	buf = GetBuffer(bodyLen)
+	if _, err = io.ReadFull(reader, buf.Bytes()); err != nil {
+		PutBuffer(buf)
+		return nil, err
+	}
+	p.Body = buf.Bytes()
+	return buf, nil
+}
+
+// readHeader 读取并解析头信息, 头信息直接在bufio的缓冲中解析, 不额外分配内存
+func readHeader(p *protocol.Proto, reader *bufio.Reader, maxBody int) (bodyLen int, err error) {
+	var buf []byte
+	if buf, err = reader.Peek(RawHeaderSize); err != nil {
+		return
+	}
+	if bodyLen, err = decodeHeader(buf, p, maxBody); err != nil {
+		return
+	}
+	_, err = reader.Discard(RawHeaderSize)
+	return
 }
 
+// WriteTcp write a package to the bufio writer, flush to send it.
 func WriteTcp(p *protocol.Proto, writer *bufio.Writer) error {
-	var err error
-	packageSize := len(p.Body) + RawHeaderSize
-	tmp := make([]byte, packageSize)
-	// 封装头信息
-	binary.BigEndian.PutUint32(tmp[PackIndex:HeaderIndex], uint32(packageSize))
-	binary.BigEndian.PutUint16(tmp[HeaderIndex:VersionIndex], uint16(RawHeaderSize))
-	binary.BigEndian.PutUint16(tmp[VersionIndex:OperateIndex], uint16(p.Ver))
-	binary.BigEndian.PutUint32(tmp[OperateIndex:SequenceIndex], uint32(p.Op))
-	binary.BigEndian.PutUint32(tmp[SequenceIndex:RawHeaderSize], uint32(p.Seq))
-	copy(tmp[RawHeaderSize:], p.Body)
-
-	//写入bufio中
-	if _, err = writer.Write(tmp); err != nil {
+	hdr := GetBuffer(RawHeaderSize)
+	defer PutBuffer(hdr)
+	encodeHeader(hdr.Bytes(), p, len(p.Body))
+	if _, err := writer.Write(hdr.Bytes()); err != nil {
 		return err
 	}
-	//bufio有缓存，flush真正写数据
-	//if err = writer.Flush(); err != nil {
-	//	return err
-	//}
-	return nil
+	_, err := writer.Write(p.Body)
+	return err
 }
 
-// WriteTCPHeart write TCP heartbeat with room online.
-func WriteTCPHeart(p *protocol.Proto, wr *bufio.Writer, online int32) error {
-	var err error
-	packageSize := len(p.Body) + RawHeaderSize
-	tmp := make([]byte, packageSize)
-	// 封装头信息
-	binary.BigEndian.PutUint32(tmp[PackIndex:HeaderIndex], uint32(packageSize))
-	binary.BigEndian.PutUint16(tmp[HeaderIndex:VersionIndex], uint16(RawHeaderSize))
-	binary.BigEndian.PutUint16(tmp[VersionIndex:OperateIndex], uint16(p.Ver))
-	binary.BigEndian.PutUint32(tmp[OperateIndex:SequenceIndex], uint32(p.Op))
-	binary.BigEndian.PutUint32(tmp[SequenceIndex:RawHeaderSize], uint32(p.Seq))
-	p.Body = []byte(strconv.Itoa(int(online)))
-	copy(tmp[RawHeaderSize:], p.Body)
-	if _, err = wr.Write(tmp); err != nil {
-		return err
+// vector is the pooled header and iovecs of a vectored write.
+type vector struct {
+	hdr  [RawHeaderSize]byte
+	bufs net.Buffers
+	iov  [2][]byte
+}
+
+var vectorPool = sync.Pool{
+	New: func() interface{} {
+		return new(vector)
+	},
+}
+
+// WriteTo write a package to the conn with a single write of the header and
+// body, see writeVector.
+func WriteTo(w io.Writer, p *protocol.Proto) error {
+	v := vectorPool.Get().(*vector)
+	encodeHeader(v.hdr[:], p, len(p.Body))
+	err := v.write(w, p.Body)
+	vectorPool.Put(v)
+	return err
+}
+
+// WriteFrame write an encoded package with its seq replaced, the package is
+// shared by many connections and never modified.
+func WriteFrame(w io.Writer, pkg []byte, seq int32) error {
+	v := vectorPool.Get().(*vector)
+	copy(v.hdr[:], pkg[:RawHeaderSize])
+	binary.BigEndian.PutUint32(v.hdr[SequenceIndex:RawHeaderSize], uint32(seq))
+	err := v.write(w, pkg[RawHeaderSize:])
+	vectorPool.Put(v)
+	return err
+}
+
+// write write the header and body in one call. The tcp and unix conns use
+// writev without copying the body, the others such as tls conns get them
+// copied into a pooled buffer, or each Write would be sent as a tls record.
+func (v *vector) write(w io.Writer, body []byte) (err error) {
+	switch w.(type) {
+	case *net.TCPConn, *net.UnixConn:
+		v.iov[0], v.iov[1] = v.hdr[:], body
+		//WriteTo会消费bufs, 每次重新指向iov
+		v.bufs = v.iov[:]
+		_, err = v.bufs.WriteTo(w)
+		v.iov[1] = nil
+		return
 	}
-	return nil
+	buf := GetBuffer(RawHeaderSize + len(body))
+	copy(buf.Bytes(), v.hdr[:])
+	copy(buf.Bytes()[RawHeaderSize:], body)
+	_, err = w.Write(buf.Bytes())
+	PutBuffer(buf)
+	return
 }
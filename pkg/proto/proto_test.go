@@ -0,0 +1,250 @@
+package proto
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"testing"
+
+	"go-im/api/protocol"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	for _, p := range []*protocol.Proto{
+		{Ver: 1, Op: protocol.OpSendMsg, Seq: 7, Body: []byte("hello")},
+		{Ver: 1, Op: protocol.OpHeartbeat, Seq: 8},
+	} {
+		buf := Encode(p)
+		if len(buf) != RawHeaderSize+len(p.Body) {
+			t.Fatalf("wrong package size %d", len(buf))
+		}
+		got := new(protocol.Proto)
+		if err := Decode(buf, got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Ver != p.Ver || got.Op != p.Op || got.Seq != p.Seq || !bytes.Equal(got.Body, p.Body) {
+			t.Fatalf("got %v want %v", got, p)
+		}
+	}
+	buf := Encode(&protocol.Proto{Op: protocol.OpSendMsg, Body: []byte("hello")})
+	if err := Decode(buf[:len(buf)-1], new(protocol.Proto)); err != ErrPackageLen {
+		t.Fatalf("truncated package err %v", err)
+	}
+	buf[HeaderIndex+1] = 0
+	if err := Decode(buf, new(protocol.Proto)); err != ErrHeaderLen {
+		t.Fatalf("wrong header err %v", err)
+	}
+}
+
+func TestReadWriteTcp(t *testing.T) {
+	ps := []*protocol.Proto{
+		{Ver: 1, Op: protocol.OpHeartbeat, Seq: 1},
+		{Ver: 1, Op: protocol.OpSendMsg, Seq: 2, Body: []byte("hello")},
+		{Ver: 1, Op: protocol.OpHeartbeat, Seq: 3},
+	}
+	var b bytes.Buffer
+	wr := bufio.NewWriter(&b)
+	for _, p := range ps {
+		if err := WriteTcp(p, wr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := wr.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	rr := bufio.NewReader(&b)
+	for _, p := range ps {
+		got := new(protocol.Proto)
+		if err := ReadTcp(got, rr); err != nil {
+			t.Fatal(err)
+		}
+		if got.Op != p.Op || got.Seq != p.Seq || !bytes.Equal(got.Body, p.Body) {
+			t.Fatalf("got %v want %v", got, p)
+		}
+	}
+}
+
+func TestReadTcpBuf(t *testing.T) {
+	var b bytes.Buffer
+	b.Write(Encode(&protocol.Proto{Op: protocol.OpSendMsg, Body: []byte("hello")}))
+	b.Write(Encode(&protocol.Proto{Op: protocol.OpHeartbeat}))
+	rr := bufio.NewReader(&b)
+	p := new(protocol.Proto)
+	buf, err := ReadTcpBuf(p, rr, MaxBodySize)
+	if err != nil || buf == nil || string(p.Body) != "hello" {
+		t.Fatalf("read body %q buf %v err %v", p.Body, buf, err)
+	}
+	PutBuffer(buf)
+	if buf, err = ReadTcpBuf(p, rr, MaxBodySize); err != nil || buf != nil || p.Body != nil {
+		t.Fatalf("read empty body %q buf %v err %v", p.Body, buf, err)
+	}
+	// truncated body
+	b.Write(Encode(&protocol.Proto{Op: protocol.OpSendMsg, Body: []byte("hello")})[:RawHeaderSize+2])
+	if _, err = ReadTcpBuf(p, rr, MaxBodySize); err == nil {
+		t.Fatal("truncated package should fail")
+	}
+}
+
+func TestWriteTo(t *testing.T) {
+	var b bytes.Buffer
+	p := &protocol.Proto{Ver: 1, Op: protocol.OpSendMsg, Seq: 3, Body: []byte("hello")}
+	if err := WriteTo(&b, p); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b.Bytes(), Encode(p)) {
+		t.Fatalf("vectored write %v want %v", b.Bytes(), Encode(p))
+	}
+
+	// a tls conn gets the package in one write, or it's sent as two records
+	w := &countWriter{}
+	if err := WriteTo(w, p); err != nil || w.writes != 1 || !bytes.Equal(w.Bytes(), Encode(p)) {
+		t.Fatalf("writes %d %v", w.writes, err)
+	}
+	w = &countWriter{}
+	if err := WriteFrame(w, Encode(p), 9); err != nil || w.writes != 1 || len(w.Bytes()) != RawHeaderSize+len(p.Body) {
+		t.Fatalf("frame writes %d %v", w.writes, err)
+	}
+}
+
+// countWriter count the Write calls.
+type countWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *countWriter) Write(b []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(b)
+}
+
+func TestBufferClass(t *testing.T) {
+	for _, size := range []int{1, 16, 17, 100, 4096, 4112, 1 << 16} {
+		buf := GetBuffer(size)
+		if len(buf.Bytes()) != size || cap(buf.Bytes()) < size {
+			t.Fatalf("size %d got len %d cap %d", size, len(buf.Bytes()), cap(buf.Bytes()))
+		}
+		PutBuffer(buf)
+	}
+	if buf := GetBuffer(1<<16 + 1); buf.class != -1 {
+		t.Fatalf("large buffer should not be pooled")
+	}
+}
+
+var benchBody = bytes.Repeat([]byte("x"), 512)
+
+// loopReader replays the same package forever.
+type loopReader struct {
+	pkg []byte
+	off int
+}
+
+func (r *loopReader) Read(b []byte) (n int, err error) {
+	for n < len(b) {
+		c := copy(b[n:], r.pkg[r.off:])
+		n += c
+		r.off = (r.off + c) % len(r.pkg)
+	}
+	return
+}
+
+func BenchmarkReadTcp(b *testing.B) {
+	rr := bufio.NewReader(&loopReader{pkg: Encode(&protocol.Proto{Op: protocol.OpSendMsg, Body: benchBody})})
+	p := new(protocol.Proto)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := ReadTcp(p, rr); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReadTcpBuf(b *testing.B) {
+	rr := bufio.NewReader(&loopReader{pkg: Encode(&protocol.Proto{Op: protocol.OpSendMsg, Body: benchBody})})
+	p := new(protocol.Proto)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf, err := ReadTcpBuf(p, rr, MaxBodySize)
+		if err != nil {
+			b.Fatal(err)
+		}
+		PutBuffer(buf)
+	}
+}
+
+func BenchmarkWriteTcp(b *testing.B) {
+	wr := bufio.NewWriter(io.Discard)
+	p := &protocol.Proto{Op: protocol.OpSendMsg, Body: benchBody}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := WriteTcp(p, wr); err != nil {
+			b.Fatal(err)
+		}
+		if err := wr.Flush(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWriteTo(b *testing.B) {
+	p := &protocol.Proto{Op: protocol.OpSendMsg, Body: benchBody}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := WriteTo(io.Discard, p); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestWriteFrame(t *testing.T) {
+	p := &protocol.Proto{Ver: 1, Op: protocol.OpSendMsg, Body: []byte("hello")}
+	pkg := Encode(p)
+	var b bytes.Buffer
+	if err := WriteFrame(&b, pkg, 42); err != nil {
+		t.Fatal(err)
+	}
+	got := new(protocol.Proto)
+	if err := Decode(b.Bytes(), got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Seq != 42 || string(got.Body) != "hello" {
+		t.Fatalf("got %v", got)
+	}
+	if !bytes.Equal(pkg, Encode(p)) {
+		t.Fatal("shared package modified")
+	}
+}
+
+func TestBatch(t *testing.T) {
+	ps := []*protocol.Proto{
+		{Ver: 1, Op: 1000, Body: []byte("a")},
+		{Ver: 1, Op: 1001},
+		{Ver: 1, Op: 1002, Body: []byte("hello")},
+	}
+	var batch []byte
+	for _, p := range ps {
+		batch = AppendPackage(batch, p)
+	}
+	got, err := SplitBatch(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(ps) {
+		t.Fatalf("got %d protos want %d", len(got), len(ps))
+	}
+	for i, p := range ps {
+		if got[i].Op != p.Op || !bytes.Equal(got[i].Body, p.Body) {
+			t.Fatalf("got %v want %v", got[i], p)
+		}
+	}
+	if _, err = SplitBatch(batch[:len(batch)-1]); err != ErrPackageLen {
+		t.Fatalf("truncated batch err %v", err)
+	}
+}
+
+func TestReadTcpBodyLimit(t *testing.T) {
+	var b bytes.Buffer
+	b.Write(Encode(&protocol.Proto{Op: protocol.OpSendMsg, Body: bytes.Repeat([]byte("x"), 10)}))
+	if _, err := ReadTcpBuf(new(protocol.Proto), bufio.NewReader(&b), 9); err != ErrBodyLen {
+		t.Fatalf("body over limit err %v", err)
+	}
+}
@@ -20,7 +20,6 @@ func main() {
 		}
 		go handler(conn)
 	}
-	select {}
 }
 
 func handler(conn net.Conn) {
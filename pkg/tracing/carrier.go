@@ -0,0 +1,104 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	kafka "github.com/Shopify/sarama"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"google.golang.org/grpc/metadata"
+)
+
+// producerCarrier carry the trace context in the headers of a message to publish.
+type producerCarrier struct {
+	m *kafka.ProducerMessage
+}
+
+func (c producerCarrier) Get(key string) string {
+	for _, h := range c.m.Headers {
+		if string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func (c producerCarrier) Set(key, val string) {
+	for i, h := range c.m.Headers {
+		if string(h.Key) == key {
+			c.m.Headers[i].Value = []byte(val)
+			return
+		}
+	}
+	c.m.Headers = append(c.m.Headers, kafka.RecordHeader{Key: []byte(key), Value: []byte(val)})
+}
+
+func (c producerCarrier) Keys() []string {
+	keys := make([]string, 0, len(c.m.Headers))
+	for _, h := range c.m.Headers {
+		keys = append(keys, string(h.Key))
+	}
+	return keys
+}
+
+// consumerCarrier read the trace context from the headers of a consumed message.
+type consumerCarrier []*kafka.RecordHeader
+
+func (c consumerCarrier) Get(key string) string {
+	for _, h := range c {
+		if h != nil && string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func (c consumerCarrier) Set(string, string) {}
+
+func (c consumerCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for _, h := range c {
+		if h != nil {
+			keys = append(keys, string(h.Key))
+		}
+	}
+	return keys
+}
+
+// metadataCarrier carry the trace context in the grpc metadata.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if vals := metadata.MD(c).Get(key); len(vals) > 0 {
+		return vals[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, val string) {
+	metadata.MD(c).Set(key, val)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// InjectKafka write the trace context of ctx into the message headers.
+func InjectKafka(ctx context.Context, m *kafka.ProducerMessage) {
+	otel.GetTextMapPropagator().Inject(ctx, producerCarrier{m: m})
+}
+
+// ExtractKafka get the trace context from the consumed message headers.
+func ExtractKafka(ctx context.Context, headers []*kafka.RecordHeader) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, consumerCarrier(headers))
+}
+
+// ExtractHTTP get the trace context from the request headers.
+func ExtractHTTP(ctx context.Context, h http.Header) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(h))
+}
@@ -0,0 +1,46 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	kafka "github.com/Shopify/sarama"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+func testContext(t *testing.T) (context.Context, trace.SpanContext) {
+	tid, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	sid, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	sc := trace.NewSpanContext(trace.SpanContextConfig{TraceID: tid, SpanID: sid, TraceFlags: trace.FlagsSampled})
+	return trace.ContextWithSpanContext(context.Background(), sc), sc
+}
+
+func TestKafkaCarrier(t *testing.T) {
+	ctx, sc := testContext(t)
+	m := &kafka.ProducerMessage{Headers: []kafka.RecordHeader{{Key: []byte("k"), Value: []byte("v")}}}
+	InjectKafka(ctx, m)
+	InjectKafka(ctx, m)
+	if len(m.Headers) != 2 {
+		t.Fatalf("headers %v", m.Headers)
+	}
+	headers := make([]*kafka.RecordHeader, 0, len(m.Headers))
+	for i := range m.Headers {
+		headers = append(headers, &m.Headers[i])
+	}
+	got := trace.SpanContextFromContext(ExtractKafka(context.Background(), headers))
+	if got.TraceID() != sc.TraceID() || got.SpanID() != sc.SpanID() || !got.IsRemote() {
+		t.Fatalf("got %v want %v", got, sc)
+	}
+}
+
+func TestMetadataCarrier(t *testing.T) {
+	ctx, sc := testContext(t)
+	md := metadata.MD{}
+	otel.GetTextMapPropagator().Inject(ctx, metadataCarrier(md))
+	got := trace.SpanContextFromContext(otel.GetTextMapPropagator().Extract(context.Background(), metadataCarrier(md)))
+	if got.TraceID() != sc.TraceID() || got.SpanID() != sc.SpanID() {
+		t.Fatalf("got %v want %v", got, sc)
+	}
+}
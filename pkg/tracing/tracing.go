@@ -0,0 +1,95 @@
+// Package tracing set up the opentelemetry tracing and propagate the trace
+// context through http headers, kafka message headers and grpc metadata.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// ExporterOTLP export spans to an OTLP collector by grpc.
+	ExporterOTLP = "otlp"
+	// ExporterStdout print spans to stdout, for debugging.
+	ExporterStdout = "stdout"
+
+	tracerName = "go-im"
+)
+
+// ErrExporter the exporter is unknown.
+var ErrExporter = errors.New("unknown trace exporter")
+
+// Config is the tracing config of a service.
+type Config struct {
+	Exporter    string  //otlp or stdout, 为空不导出
+	Endpoint    string  //otlp collector的grpc地址
+	SampleRatio float64 //根span的采样率, 子span跟随上游
+}
+
+func init() {
+	//未初始化时也传递trace上下文, 上游采样的消息仍能串起来
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+// Init set the global tracer provider of the service, spans are exported to
+// the OTLP collector at endpoint, or stdout. ratio is the sample ratio of the
+// root spans, the child spans follow the parent. The returned shutdown
+// flushes the pending spans.
+func Init(service, exporter, endpoint string, ratio float64) (shutdown func(context.Context) error, err error) {
+	var exp sdktrace.SpanExporter
+	switch exporter {
+	case ExporterOTLP:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithInsecure()}
+		if endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(endpoint))
+		}
+		exp, err = otlptracegrpc.New(context.Background(), opts...)
+	case ExporterStdout:
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		err = ErrExporter
+	}
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(service)))
+	if err != nil {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// Setup init the tracing of the service by the config, nothing is exported if c
+// is nil or without the exporter. The returned stop flushes the pending spans,
+// call it before exiting.
+func Setup(service string, c *Config) (stop func(), err error) {
+	if c == nil || c.Exporter == "" {
+		return func() {}, nil
+	}
+	shutdown, err := Init(service, c.Exporter, c.Endpoint, c.SampleRatio)
+	if err != nil {
+		return nil, err
+	}
+	return func() { _ = shutdown(context.Background()) }, nil
+}
+
+// Tracer get the tracer of the global provider, it's a noop before Init.
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
@@ -0,0 +1,18 @@
+package tracing
+
+import (
+	"testing"
+)
+
+func TestSetup(t *testing.T) {
+	for _, c := range []*Config{nil, {Endpoint: "127.0.0.1:4317"}} {
+		stop, err := Setup("test", c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stop()
+	}
+	if _, err := Setup("test", &Config{Exporter: "zipkin"}); err != ErrExporter {
+		t.Fatalf("unknown exporter %v", err)
+	}
+}